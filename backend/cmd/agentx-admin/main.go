@@ -0,0 +1,192 @@
+// Command agentx-admin is the operational CLI for go-backend: schema
+// migrations, one-off seeding, and backfills that used to live in
+// separate one-shot scripts like the old cmd/init_mcc.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-backend/internal/config"
+	"go-backend/internal/database"
+	"go-backend/internal/logging"
+	"go-backend/internal/migrate"
+	"go-backend/internal/models"
+	"go-backend/internal/seed"
+	"go-backend/internal/tools"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	logger := logging.New().Named("agentx-admin")
+	cfg := config.Load()
+
+	if err := database.Connect(cfg.DatabaseURL); err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	cwd, _ := os.Getwd()
+	projectRoot := filepath.Dir(cwd)
+	if filepath.Base(cwd) != "go-backend" {
+		projectRoot = cwd
+	}
+
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "migrate":
+		runMigrate(logger, args)
+	case "seed":
+		runSeed(logger, projectRoot, args)
+	case "backfill":
+		runBackfill(logger, args)
+	case "registry":
+		runRegistry(logger, projectRoot, args)
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println(`agentx-admin - operational CLI for go-backend
+
+Usage:
+  agentx-admin migrate up|down|status
+  agentx-admin seed mcc|tools|agents
+  agentx-admin backfill executions-from-tasks
+  agentx-admin registry sync`)
+}
+
+func runMigrate(logger hclog.Logger, args []string) {
+	if len(args) < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrate.Up()
+		if err != nil {
+			logger.Error("migrate up failed", "error", err)
+			os.Exit(1)
+		}
+		if len(applied) == 0 {
+			logger.Info("nothing to apply")
+			return
+		}
+		logger.Info("applied migrations", "migrations", applied)
+
+	case "down":
+		reverted, err := migrate.Down()
+		if err != nil {
+			logger.Error("migrate down failed", "error", err)
+			os.Exit(1)
+		}
+		if reverted == "" {
+			logger.Info("nothing to revert")
+			return
+		}
+		logger.Info("reverted migration", "migration", reverted)
+
+	case "status":
+		entries, err := migrate.Status()
+		if err != nil {
+			logger.Error("migrate status failed", "error", err)
+			os.Exit(1)
+		}
+		for _, e := range entries {
+			state := "pending"
+			if e.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%4d  %-30s %s\n", e.Version, e.Name, state)
+		}
+
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func runSeed(logger hclog.Logger, projectRoot string, args []string) {
+	if len(args) < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "mcc":
+		if err := models.InitMccTables(); err != nil {
+			logger.Error("init mcc tables failed", "error", err)
+			os.Exit(1)
+		}
+		jsonPath := filepath.Join(projectRoot, "database", "mcc_master.json")
+		if err := seed.SeedMccCodes(database.DB, jsonPath); err != nil {
+			logger.Error("seed mcc failed", "path", jsonPath, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("mcc codes seeded", "path", jsonPath)
+
+	case "tools":
+		runRegistry(logger, projectRoot, []string{"sync"})
+
+	case "agents":
+		logger.Error("seed agents is not implemented: agents are currently created directly through the API, not from a seed file")
+		os.Exit(1)
+
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func runBackfill(logger hclog.Logger, args []string) {
+	if len(args) < 1 || args[0] != "executions-from-tasks" {
+		usage()
+		os.Exit(1)
+	}
+
+	if err := models.InitExecutionTables(); err != nil {
+		logger.Error("init execution tables failed", "error", err)
+		os.Exit(1)
+	}
+
+	created, err := models.NewExecutionRepository().BackfillFromOrphanTasks()
+	if err != nil {
+		logger.Error("backfill executions-from-tasks failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("backfilled executions from tasks", "created", created)
+}
+
+func runRegistry(logger hclog.Logger, projectRoot string, args []string) {
+	if len(args) < 1 || args[0] != "sync" {
+		usage()
+		os.Exit(1)
+	}
+
+	if err := models.InitToolRegistryTables(); err != nil {
+		logger.Error("init tool registry tables failed", "error", err)
+		os.Exit(1)
+	}
+
+	manifestPath := filepath.Join(projectRoot, "database", "tools_manifest.json")
+	if err := tools.InitRegistry(manifestPath); err != nil {
+		logger.Error("load tools manifest failed", "path", manifestPath, "error", err)
+		os.Exit(1)
+	}
+	if err := models.NewToolRegistryRepository().SyncAll(tools.ListTools()); err != nil {
+		logger.Error("sync tool registry failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("tool registry synced", "path", manifestPath, "count", len(tools.ListTools()))
+}
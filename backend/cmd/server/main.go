@@ -1,22 +1,31 @@
 package main
 
 import (
-	"log"
+	"context"
+	"encoding/json"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
+	"go-backend/internal/assistants"
+	"go-backend/internal/callbacks"
 	"go-backend/internal/config"
 	"go-backend/internal/database"
 	"go-backend/internal/handlers"
+	"go-backend/internal/logging"
 	"go-backend/internal/middleware"
+	"go-backend/internal/models"
+	"go-backend/internal/scheduler"
 	"go-backend/internal/tools"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	logger := logging.New()
+
 	// Load configuration
 	cfg := config.Load()
 
@@ -25,10 +34,32 @@ func main() {
 
 	// Connect to database
 	if err := database.Connect(cfg.DatabaseURL); err != nil {
-		log.Fatalf("❌ Failed to connect to database: %v", err)
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 	defer database.Close()
-	log.Println("✅ Database connected")
+	logger.Info("database connected")
+
+	if err := models.InitExecutionTables(); err != nil {
+		logger.Error("failed to init execution tables", "error", err)
+		os.Exit(1)
+	}
+	if err := models.InitScheduledJobTables(); err != nil {
+		logger.Error("failed to init scheduled job tables", "error", err)
+		os.Exit(1)
+	}
+	if err := models.InitToolRegistryTables(); err != nil {
+		logger.Error("failed to init tool registry tables", "error", err)
+		os.Exit(1)
+	}
+	if err := models.InitTaskCallbackTables(); err != nil {
+		logger.Error("failed to init task callback tables", "error", err)
+		os.Exit(1)
+	}
+	if err := models.InitTaskLeaseTables(); err != nil {
+		logger.Error("failed to init task lease tables", "error", err)
+		os.Exit(1)
+	}
 
 	// Get project root (parent of go-backend)
 	cwd, _ := os.Getwd()
@@ -37,32 +68,118 @@ func main() {
 		// If running from project root, use current directory
 		projectRoot = cwd
 	}
-	log.Printf("📁 Project root: %s", projectRoot)
+	logger.Info("project root resolved", "path", projectRoot)
+
+	// Load the tool registry from its manifest, then persist it so a
+	// restart before the next reload doesn't lose registrations. If the
+	// manifest is missing or invalid, fall back to whatever was
+	// persisted from the last successful load rather than refusing to
+	// start.
+	toolsManifestPath := filepath.Join(projectRoot, "database", "tools_manifest.json")
+	toolRegistryRepo := models.NewToolRegistryRepository()
+	if err := tools.InitRegistry(toolsManifestPath); err != nil {
+		logger.Warn("failed to load tools manifest; falling back to persisted registry", "path", toolsManifestPath, "error", err)
+		persisted, findErr := toolRegistryRepo.FindAll()
+		if findErr != nil || len(persisted) == 0 {
+			logger.Error("failed to load tools manifest and no persisted registry is available", "manifest_error", err, "db_error", findErr)
+			os.Exit(1)
+		}
+		tools.InitRegistryFromTools(persisted)
+		logger.Warn("loaded tool registry from the database fallback", "count", len(persisted))
+	} else if err := toolRegistryRepo.SyncAll(tools.ListTools()); err != nil {
+		logger.Warn("failed to persist tool registry", "error", err)
+	}
+	logger.Info("tools manifest loaded", "path", toolsManifestPath, "count", len(tools.ListTools()))
 
 	// Create executor with hybrid concurrency control
 	executor := tools.NewExecutor(
 		cfg.GlobalConcurrencyLimit,
 		cfg.DefaultToolConcurrencyLimit,
 		projectRoot,
+		logger.Named("executor"),
 	)
-	log.Printf("⚡ Executor initialized (global: %d, per-tool default: %d)",
-		cfg.GlobalConcurrencyLimit,
-		cfg.DefaultToolConcurrencyLimit,
+	logger.Info("executor initialized",
+		"global_limit", cfg.GlobalConcurrencyLimit,
+		"default_tool_limit", cfg.DefaultToolConcurrencyLimit,
 	)
 
+	// Docker and remote backends are opt-in: a tool only reaches one if
+	// its manifest entry sets backend to "docker" or "remote", so an
+	// operator who hasn't configured either never pays for them.
+	if cfg.DockerSocketPath != "" {
+		executor.RegisterBackend("docker", tools.NewDockerBackend(cfg.DockerSocketPath))
+		logger.Info("docker backend registered", "socket", cfg.DockerSocketPath)
+	}
+	if cfg.RemoteWorkersJSON != "" {
+		var workers []tools.RemoteWorker
+		if err := json.Unmarshal([]byte(cfg.RemoteWorkersJSON), &workers); err != nil {
+			logger.Error("failed to parse REMOTE_WORKERS", "error", err)
+			os.Exit(1)
+		}
+		executor.RegisterBackend("remote", tools.NewRemoteBackend(workers))
+		logger.Info("remote backend registered", "workers", len(workers))
+	}
+
+	// Wire up task lease renewal/expiry, then start the supervisor that
+	// fails tasks left processing by a crashed backend instance
+	leaseTaskRepo := models.NewTaskRepository()
+	leaseExecutionRepo := models.NewExecutionRepository()
+	executor.ConfigureLeaseSupervisor(
+		leaseTaskRepo.Heartbeat,
+		leaseTaskRepo.FindStaleProcessing,
+		func(taskID string) error {
+			task, err := leaseTaskRepo.FindByID(taskID)
+			if err != nil || task == nil {
+				return err
+			}
+			if _, err := leaseTaskRepo.UpdateFailed(taskID, "task lease expired: no heartbeat received, the owning backend instance may have crashed"); err != nil {
+				return err
+			}
+			if task.ExecutionID != nil {
+				_, err := leaseExecutionRepo.RecalculateCounters(*task.ExecutionID)
+				return err
+			}
+			return nil
+		},
+	)
+	executor.StartLeaseSupervisor()
+
+	// Start the assistant plugin manager (persistent Python processes,
+	// supervised and multiplexed over stdio JSON-RPC)
+	assistants.InitRegistry()
+	assistantManager := assistants.NewManager(assistants.List(), projectRoot, logger.Named("assistants"))
+	if err := assistantManager.Start(); err != nil {
+		logger.Error("failed to start assistant plugins", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("assistant plugin manager started")
+
 	// Create Gin router
 	router := gin.New()
 
 	// Middleware
 	router.Use(gin.Recovery())
-	router.Use(middleware.LoggingMiddleware())
+	router.Use(middleware.RequestLogger(logger))
+	router.Use(middleware.LoggingMiddleware(logger))
 	router.Use(middleware.CORSMiddleware(cfg.CORSOrigins))
 
 	// Initialize handlers
-	agentsHandler := handlers.NewAgentsHandler(executor)
-	tasksHandler := handlers.NewTasksHandler()
-	monitoringHandler := handlers.NewMonitoringHandler(executor)
-	toolsHandler := handlers.NewToolsHandler()
+	agentsHandler := handlers.NewAgentsHandler(executor, logger.Named("agents"))
+	tasksHandler := handlers.NewTasksHandler(executor, logger.Named("tasks"))
+	monitoringHandler := handlers.NewMonitoringHandler(executor, cfg.ProxyAllowedHosts, logger.Named("monitoring"))
+	toolsHandler := handlers.NewToolsHandler(executor, logger.Named("tools"))
+	assistantsHandler := handlers.NewAssistantsHandler(assistantManager, logger.Named("assistants"))
+	mccHandler := handlers.NewMccHandler()
+
+	executionRepo := models.NewExecutionRepository()
+	taskRepo := models.NewTaskRepository()
+	jobRepo := models.NewScheduledJobRepository()
+	sched := scheduler.New(models.NewAgentRepository(), executionRepo, taskRepo, jobRepo, executor, logger.Named("scheduler"))
+	sched.Start()
+	executionsHandler := handlers.NewExecutionsHandler(executionRepo, taskRepo, sched)
+
+	callbackDispatcher := callbacks.New(models.NewTaskCallbackRepository(), taskRepo, logger.Named("callbacks"))
+	callbackDispatcher.Start()
 
 	// Root endpoint
 	router.GET("/", func(c *gin.Context) {
@@ -74,6 +191,9 @@ func main() {
 				"agents":     "/api/agents",
 				"tasks":      "/api/tasks",
 				"tools":      "/api/tools",
+				"assistants": "/api/assistants",
+				"mccs":       "/api/mccs",
+				"executions": "/api/executions",
 				"monitoring": "/api/monitoring",
 				"health":     "/api/monitoring/health",
 			},
@@ -87,10 +207,30 @@ func main() {
 		agents := api.Group("/agents")
 		{
 			agents.GET("", agentsHandler.GetAll)
+			agents.GET("/summary", agentsHandler.Summary)
+			agents.POST("/reload", agentsHandler.Reload)
 			agents.GET("/:id", agentsHandler.GetByID)
 			agents.POST("/:name/execute", agentsHandler.Execute) // Uses agent type (e.g., "market-research")
 			agents.PUT("/:id", agentsHandler.Update)
 			agents.GET("/:id/metrics", agentsHandler.GetMetrics)
+			agents.GET("/tasks/:id/stream", agentsHandler.Stream)
+		}
+
+		// MCC (merchant category code) routes
+		mccs := api.Group("/mccs")
+		{
+			mccs.GET("", mccHandler.GetMccs)
+			mccs.GET("/summary", mccHandler.AuditSummary)
+		}
+
+		// Executions routes (manual/scheduled/event-triggered fan-out runs)
+		executions := api.Group("/executions")
+		{
+			executions.GET("", executionsHandler.List)
+			executions.POST("", executionsHandler.Create)
+			executions.POST("/events/:agentType", executionsHandler.Event)
+			executions.GET("/:id", executionsHandler.GetByID)
+			executions.POST("/:id/stop", executionsHandler.Stop)
 		}
 
 		// Tasks routes
@@ -99,6 +239,8 @@ func main() {
 			tasks.GET("", tasksHandler.GetAll)
 			tasks.GET("/status/counts", tasksHandler.GetStatusCounts)
 			tasks.GET("/:id", tasksHandler.GetByID)
+			tasks.POST("/:id/resume", tasksHandler.Resume)
+			tasks.POST("/:id/retry", tasksHandler.Retry)
 		}
 
 		// Tools routes (new endpoint)
@@ -108,42 +250,88 @@ func main() {
 			toolsGroup.GET("/:name", toolsHandler.GetTool)
 		}
 
+		// Executor stats (queued/in-flight/rejected job counters)
+		executorGroup := api.Group("/executor")
+		{
+			executorGroup.GET("/stats", toolsHandler.Stats)
+		}
+
+		// Assistants routes (LLM chat, streamed or blocking)
+		assistantsGroup := api.Group("/assistants")
+		{
+			assistantsGroup.GET("", assistantsHandler.List)
+			assistantsGroup.POST("/:name/chat", assistantsHandler.Chat)
+			assistantsGroup.GET("/:name/chat/stream", assistantsHandler.ChatStream)
+			assistantsGroup.POST("/:name/chat/stream", assistantsHandler.ChatStream)
+		}
+
 		// Monitoring routes
 		monitoring := api.Group("/monitoring")
 		{
 			monitoring.GET("/health", monitoringHandler.Health)
 			monitoring.GET("/metrics", monitoringHandler.Metrics)
+			monitoring.GET("/metrics/prometheus", monitoringHandler.MetricsPrometheus)
 			monitoring.GET("/activity", monitoringHandler.Activity)
 			monitoring.GET("/system", monitoringHandler.System)
 			monitoring.GET("/proxy", monitoringHandler.Proxy)
 		}
 	}
 
+	// Hot-reload the tools manifest on SIGHUP, so operators can add or
+	// change agents without restarting the server.
+	go func() {
+		reloadChan := make(chan os.Signal, 1)
+		signal.Notify(reloadChan, syscall.SIGHUP)
+		for range reloadChan {
+			added, removed, err := tools.ReloadRegistry()
+			if err != nil {
+				logger.Error("tools manifest reload failed", "error", err)
+				continue
+			}
+			if err := toolRegistryRepo.SyncAll(tools.ListTools()); err != nil {
+				logger.Warn("failed to persist reloaded tool registry", "error", err)
+			}
+			logger.Info("tools manifest reloaded", "added", added, "removed", removed)
+		}
+	}()
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
-		log.Println("🛑 Shutting down gracefully...")
+		logger.Info("shutting down gracefully")
+
+		drainCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := assistantManager.Drain(drainCtx); err != nil {
+			logger.Warn("assistant manager drain error", "error", err)
+		}
+		sched.Stop()
+		callbackDispatcher.Stop()
+		executor.StopLeaseSupervisor()
+
 		database.Close()
 		os.Exit(0)
 	}()
 
 	// Start server
 	addr := ":" + cfg.Port
-	log.Printf("🚀 Server starting on port %s", cfg.Port)
-	log.Printf("📡 Environment: %s", cfg.GinMode)
-	log.Printf("🌐 CORS enabled for: %v", cfg.CORSOrigins)
-	log.Println("")
-	log.Println("📍 API Documentation:")
-	log.Printf("   - Health: http://localhost:%s/api/monitoring/health", cfg.Port)
-	log.Printf("   - Agents: http://localhost:%s/api/agents", cfg.Port)
-	log.Printf("   - Tasks:  http://localhost:%s/api/tasks", cfg.Port)
-	log.Printf("   - Tools:  http://localhost:%s/api/tools", cfg.Port)
-	log.Println("")
+	logger.Info("server starting",
+		"port", cfg.Port,
+		"environment", cfg.GinMode,
+		"cors_origins", cfg.CORSOrigins,
+	)
+	logger.Info("api documentation",
+		"health", "http://localhost:"+cfg.Port+"/api/monitoring/health",
+		"agents", "http://localhost:"+cfg.Port+"/api/agents",
+		"tasks", "http://localhost:"+cfg.Port+"/api/tasks",
+		"tools", "http://localhost:"+cfg.Port+"/api/tools",
+	)
 
 	if err := router.Run(addr); err != nil {
-		log.Fatalf("❌ Failed to start server: %v", err)
+		logger.Error("failed to start server", "error", err)
+		os.Exit(1)
 	}
 }
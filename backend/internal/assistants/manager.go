@@ -0,0 +1,494 @@
+package assistants
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+)
+
+// Frame is one newline-delimited JSON message exchanged with a plugin.
+// Requests and responses share this shape; "id" correlates a response
+// (or stream of responses) back to the request that triggered it.
+type Frame struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"` // "chat", "token", "citation", "metadata", "done", "error", "ping", "pong"
+	Text      string                 `json:"text,omitempty"`
+	URL       string                 `json:"url,omitempty"`
+	Answer    string                 `json:"answer,omitempty"`
+	Citations []string               `json:"citations,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Payload   map[string]interface{} `json:"payload,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+const (
+	restartBackoffInitial = 500 * time.Millisecond
+	restartBackoffMax     = 30 * time.Second
+	healthCheckInterval   = 15 * time.Second
+	healthCheckTimeout    = 5 * time.Second
+)
+
+// plugin wraps a single supervised child process and the bookkeeping
+// needed to multiplex concurrent requests over its single stdio stream.
+type plugin struct {
+	cfg PluginConfig
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	pending map[string]chan Frame // keyed by request id
+	healthy bool
+	stopped bool
+}
+
+// inFlightCount returns the number of requests awaiting a response.
+func (p *plugin) inFlightCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+// Manager supervises a set of long-lived assistant plugin processes and
+// multiplexes chat requests over them by request ID, restarting any
+// process that crashes and health-checking the rest on an interval.
+type Manager struct {
+	projectRoot string
+	logger      hclog.Logger
+
+	mu      sync.RWMutex
+	plugins map[string]*plugin
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a manager for the given plugin configs. Call Start
+// to actually launch the child processes.
+func NewManager(configs []PluginConfig, projectRoot string, logger hclog.Logger) *Manager {
+	m := &Manager{
+		projectRoot: projectRoot,
+		logger:      logger,
+		plugins:     make(map[string]*plugin),
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	for _, cfg := range configs {
+		m.plugins[cfg.Name] = &plugin{cfg: cfg, pending: make(map[string]chan Frame)}
+	}
+	return m
+}
+
+// Start launches every registered plugin and begins supervising it.
+func (m *Manager) Start() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name, p := range m.plugins {
+		if err := m.spawn(p); err != nil {
+			return fmt.Errorf("starting plugin %s: %w", name, err)
+		}
+		m.wg.Add(1)
+		go m.supervise(p)
+	}
+
+	m.wg.Add(1)
+	go m.healthCheckLoop()
+
+	return nil
+}
+
+// spawn starts (or restarts) the child process for a plugin.
+func (m *Manager) spawn(p *plugin) error {
+	if p.cfg.Transport != "" && p.cfg.Transport != TransportStdio {
+		return fmt.Errorf("plugin %s: unsupported transport %q", p.cfg.Name, p.cfg.Transport)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cmd := exec.Command(p.cfg.Command, p.cfg.Args...)
+
+	workDir := p.cfg.WorkingDir
+	if workDir == "." || workDir == "" {
+		workDir = m.projectRoot
+	} else if !filepath.IsAbs(workDir) {
+		workDir = filepath.Join(m.projectRoot, workDir)
+	}
+	cmd.Dir = workDir
+
+	env := os.Environ()
+	for k, v := range p.cfg.Env {
+		env = append(env, k+"="+v)
+	}
+	cmd.Env = env
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = logWriter{logger: m.logger, name: p.cfg.Name}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	p.cmd = cmd
+	p.stdin = stdin
+	p.healthy = true
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+
+	go m.readLoop(p, stdout)
+
+	m.logger.Info("plugin started", "plugin", p.cfg.Name, "pid", cmd.Process.Pid)
+	return nil
+}
+
+// readLoop demuxes newline-delimited JSON frames from the plugin's
+// stdout, routing each to the channel waiting on its request ID.
+func (m *Manager) readLoop(p *plugin, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var frame Frame
+		if err := json.Unmarshal(line, &frame); err != nil {
+			m.logger.Warn("plugin emitted malformed frame", "plugin", p.cfg.Name, "error", err)
+			continue
+		}
+
+		p.mu.Lock()
+		ch, ok := p.pending[frame.ID]
+		p.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		// Non-blocking: a caller that abandoned this request (timed out
+		// or had its ctx cancelled) stops draining ch, and it's the only
+		// per-request buffer on a stream shared by every concurrent
+		// caller of this plugin - a blocking send here would wedge
+		// readLoop, and with it every other in-flight request.
+		select {
+		case ch <- frame:
+		default:
+			m.logger.Warn("dropping frame for unread request", "plugin", p.cfg.Name, "request_id", frame.ID)
+			continue
+		}
+		// "pong" is the single-shot reply to the "ping" healthCheckLoop
+		// sends, so it's terminal the same way "done"/"error" are for a
+		// chat request - without this, a plugin replying exactly per the
+		// documented protocol would never close out the health check,
+		// and every health check would "fail" on the 5s ctx timeout.
+		if frame.Type == "done" || frame.Type == "error" || frame.Type == "pong" {
+			p.mu.Lock()
+			delete(p.pending, frame.ID)
+			p.mu.Unlock()
+			close(ch)
+		}
+	}
+}
+
+// supervise restarts a plugin's process whenever it exits, with
+// exponential backoff, until the manager is shut down.
+func (m *Manager) supervise(p *plugin) {
+	defer m.wg.Done()
+
+	backoff := restartBackoffInitial
+	for {
+		p.mu.Lock()
+		cmd := p.cmd
+		stopped := p.stopped
+		p.mu.Unlock()
+		if stopped {
+			return
+		}
+
+		err := cmd.Wait()
+
+		p.mu.Lock()
+		p.healthy = false
+		alreadyStopped := p.stopped
+		p.mu.Unlock()
+		if alreadyStopped {
+			return
+		}
+
+		m.logger.Warn("plugin exited; restarting", "plugin", p.cfg.Name, "error", err, "backoff", backoff)
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := m.spawn(p); err != nil {
+			m.logger.Error("failed to restart plugin", "plugin", p.cfg.Name, "error", err)
+			backoff = minDuration(backoff*2, restartBackoffMax)
+			continue
+		}
+		backoff = restartBackoffInitial
+	}
+}
+
+// healthCheckLoop periodically pings every plugin so a hung-but-alive
+// process (no crash, no output) is still detected as unhealthy.
+func (m *Manager) healthCheckLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.mu.RLock()
+			plugins := make([]*plugin, 0, len(m.plugins))
+			for _, p := range m.plugins {
+				plugins = append(plugins, p)
+			}
+			m.mu.RUnlock()
+
+			for _, p := range plugins {
+				ctx, cancel := context.WithTimeout(m.ctx, healthCheckTimeout)
+				_, err := m.request(ctx, p, Frame{Type: "ping"})
+				cancel()
+
+				p.mu.Lock()
+				p.healthy = err == nil
+				p.mu.Unlock()
+
+				if err != nil {
+					m.logger.Warn("plugin failed health check", "plugin", p.cfg.Name, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// Invoke sends a blocking chat request to the named plugin and returns
+// its terminal "done" frame.
+func (m *Manager) Invoke(ctx context.Context, name string, payload map[string]interface{}) (*Frame, error) {
+	p, ok := m.plugin(name)
+	if !ok {
+		return nil, fmt.Errorf("no assistant plugin registered: %s", name)
+	}
+
+	frame, err := m.request(ctx, p, Frame{Type: "chat", Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	if frame.Type == "error" {
+		return nil, fmt.Errorf("assistant error: %s", frame.Error)
+	}
+	return frame, nil
+}
+
+// InvokeStream sends a chat request and returns a channel of every
+// frame the plugin emits for it (tokens, citations, metadata, and a
+// final "done"/"error" frame that closes the channel). unsubscribe must
+// be called once the caller stops reading - typically via defer - so an
+// abandoned request (e.g. the client disconnected) doesn't stay
+// registered in the plugin's pending map forever.
+func (m *Manager) InvokeStream(ctx context.Context, name string, payload map[string]interface{}) (frames <-chan Frame, unsubscribe func(), err error) {
+	p, ok := m.plugin(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("no assistant plugin registered: %s", name)
+	}
+	id, ch, err := m.requestStream(ctx, p, Frame{Type: "chat", Payload: payload})
+	if err != nil {
+		return nil, nil, err
+	}
+	unsubscribe = func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}
+	return ch, unsubscribe, nil
+}
+
+// request performs a single request/response round trip and returns the
+// last (terminal) frame received.
+func (m *Manager) request(ctx context.Context, p *plugin, req Frame) (*Frame, error) {
+	id, ch, err := m.requestStream(ctx, p, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var last Frame
+	for {
+		select {
+		case frame, ok := <-ch:
+			if !ok {
+				return &last, nil
+			}
+			last = frame
+		case <-ctx.Done():
+			// Abandon the request: drop its entry so readLoop stops
+			// trying to deliver frames for it instead of leaving it
+			// pending forever.
+			p.mu.Lock()
+			delete(p.pending, id)
+			p.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// requestStream writes a request frame to the plugin's stdin and
+// returns the frame's request ID and the channel its responses will be
+// delivered on.
+func (m *Manager) requestStream(ctx context.Context, p *plugin, req Frame) (id string, frames <-chan Frame, err error) {
+	req.ID = uuid.New().String()
+	ch := make(chan Frame, 8)
+
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return "", nil, fmt.Errorf("assistant plugin %s is shutting down", p.cfg.Name)
+	}
+	stdin := p.stdin
+	p.pending[req.ID] = ch
+	p.mu.Unlock()
+
+	line, marshalErr := json.Marshal(req)
+	if marshalErr != nil {
+		p.mu.Lock()
+		delete(p.pending, req.ID)
+		p.mu.Unlock()
+		return "", nil, marshalErr
+	}
+	line = append(line, '\n')
+
+	if _, writeErr := stdin.Write(line); writeErr != nil {
+		p.mu.Lock()
+		delete(p.pending, req.ID)
+		p.mu.Unlock()
+		return "", nil, fmt.Errorf("writing to plugin %s: %w", p.cfg.Name, writeErr)
+	}
+
+	return req.ID, ch, nil
+}
+
+func (m *Manager) plugin(name string) (*plugin, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.plugins[name]
+	return p, ok
+}
+
+// Discover returns a snapshot of every registered plugin's name,
+// transport, and health, for the /api/assistants listing endpoint.
+func (m *Manager) Discover() []map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]map[string]interface{}, 0, len(m.plugins))
+	for name, p := range m.plugins {
+		p.mu.Lock()
+		healthy := p.healthy
+		p.mu.Unlock()
+		out = append(out, map[string]interface{}{
+			"name":      name,
+			"transport": p.cfg.Transport,
+			"healthy":   healthy,
+		})
+	}
+	return out
+}
+
+// Drain stops accepting new work's ability to block shutdown: it waits
+// for in-flight requests to finish (bounded by ctx) and then terminates
+// every plugin process. Call this from a SIGTERM handler before exit.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.mu.RLock()
+	plugins := make([]*plugin, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		plugins = append(plugins, p)
+	}
+	m.mu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			total := 0
+			for _, p := range plugins {
+				total += p.inFlightCount()
+			}
+			if total == 0 {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		m.logger.Warn("drain timed out with requests still in flight")
+	}
+
+	m.cancel()
+
+	// Kill every process before waiting on wg: supervise's own loop is
+	// blocked inside cmd.Wait() for exactly as long as the process stays
+	// alive, which for a healthy plugin is indefinitely - waiting on wg
+	// first would deadlock Drain against the process it's trying to stop.
+	for _, p := range plugins {
+		p.mu.Lock()
+		p.stopped = true
+		cmd := p.cmd
+		p.mu.Unlock()
+		if cmd != nil && cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+
+	m.wg.Wait()
+
+	return nil
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// logWriter adapts hclog to an io.Writer so a plugin's stderr (its own
+// log output) is tagged with its name.
+type logWriter struct {
+	logger hclog.Logger
+	name   string
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.logger.Debug("plugin stderr", "plugin", w.name, "output", string(p))
+	return len(p), nil
+}
@@ -0,0 +1,170 @@
+package assistants
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// echoPluginScript is a /bin/sh stand-in for a real assistant plugin: it
+// reads newline-delimited request frames from stdin and, per the Frame
+// protocol documented on the Frame type, replies "pong" to a "ping" and a
+// burst of "token" frames followed by "done" to a "chat" - regardless of
+// whether anything is still listening, the same as a real plugin that
+// doesn't know a caller gave up.
+const echoPluginScript = `
+while IFS= read -r line; do
+	id=$(printf '%s' "$line" | sed -n 's/.*"id":"\([^"]*\)".*/\1/p')
+	type=$(printf '%s' "$line" | sed -n 's/.*"type":"\([^"]*\)".*/\1/p')
+	if [ "$type" = "ping" ]; then
+		printf '{"id":"%s","type":"pong"}\n' "$id"
+	elif [ "$type" = "chat" ]; then
+		sleep 0.2
+		i=0
+		while [ $i -lt 20 ]; do
+			printf '{"id":"%s","type":"token","text":"x"}\n' "$id"
+			i=$((i+1))
+		done
+		printf '{"id":"%s","type":"done","answer":"ok"}\n' "$id"
+	fi
+done
+`
+
+func newEchoManager(t *testing.T) *Manager {
+	t.Helper()
+	m := NewManager([]PluginConfig{{
+		Name:      "echo",
+		Transport: TransportStdio,
+		Command:   "/bin/sh",
+		Args:      []string{"-c", echoPluginScript},
+	}}, "", hclog.NewNullLogger())
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		m.Drain(ctx)
+	})
+	return m
+}
+
+// TestRequestPongClosesHealthCheck exercises the exact path
+// healthCheckLoop uses: a "ping" answered with the documented "pong"
+// reply must terminate the request without needing a ctx timeout or a
+// "done"/"error" frame.
+func TestRequestPongClosesHealthCheck(t *testing.T) {
+	m := newEchoManager(t)
+	p, ok := m.plugin("echo")
+	if !ok {
+		t.Fatal("plugin not registered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	frame, err := m.request(ctx, p, Frame{Type: "ping"})
+	if err != nil {
+		t.Fatalf("request(ping) returned an error instead of the pong reply: %v", err)
+	}
+	if frame.Type != "pong" {
+		t.Fatalf("expected a pong frame, got %q", frame.Type)
+	}
+}
+
+// TestAbandonedRequestDoesNotWedgePlugin reproduces the bug where a
+// request whose ctx is cancelled left its pending entry registered
+// forever: readLoop is the single demuxer for every concurrent caller of
+// a plugin, so once the echo script queues up more frames for that
+// abandoned request than the channel buffer holds, a blocking send would
+// stall delivery to every other request. A later, unrelated request to
+// the same plugin must still complete promptly.
+func TestAbandonedRequestDoesNotWedgePlugin(t *testing.T) {
+	m := newEchoManager(t)
+	p, ok := m.plugin("echo")
+	if !ok {
+		t.Fatal("plugin not registered")
+	}
+
+	abandonedCtx, abandonedCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer abandonedCancel()
+	if _, err := m.request(abandonedCtx, p, Frame{Type: "chat", Payload: map[string]interface{}{"message": "hi"}}); err == nil {
+		t.Fatal("expected the abandoned request to time out")
+	}
+
+	// Give the plugin time to emit its full burst of frames for the
+	// abandoned request id into an unread (or, pre-fix, never-deleted)
+	// channel.
+	time.Sleep(400 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := m.request(ctx, p, Frame{Type: "ping"}); err != nil {
+			t.Errorf("request after an abandoned request failed: %v", err)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("readLoop appears wedged by the abandoned request")
+	}
+}
+
+// TestSuperviseRestartsOnExit confirms a plugin process that exits is
+// restarted with backoff rather than left dead: the plugin's process is
+// replaced by one with a different PID.
+func TestSuperviseRestartsOnExit(t *testing.T) {
+	m := NewManager([]PluginConfig{{
+		Name:      "flaky",
+		Transport: TransportStdio,
+		Command:   "/bin/sh",
+		Args:      []string{"-c", "exit 0"},
+	}}, "", hclog.NewNullLogger())
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		m.Drain(ctx)
+	}()
+
+	p, ok := m.plugin("flaky")
+	if !ok {
+		t.Fatal("plugin not registered")
+	}
+
+	var firstPID int
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		cmd := p.cmd
+		p.mu.Unlock()
+		if cmd != nil && cmd.Process != nil {
+			firstPID = cmd.Process.Pid
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if firstPID == 0 {
+		t.Fatal("plugin never started")
+	}
+
+	deadline = time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		cmd := p.cmd
+		p.mu.Unlock()
+		if cmd != nil && cmd.Process != nil && cmd.Process.Pid != firstPID {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("plugin was never restarted after exiting")
+}
@@ -0,0 +1,70 @@
+package assistants
+
+// Transport identifies how the manager talks to a plugin process. stdio
+// is the only transport the Manager actually implements (spawn wires up
+// the child's stdin/stdout directly); the field exists so a future
+// transport can be added without changing PluginConfig's shape again.
+type Transport string
+
+const (
+	// TransportStdio speaks newline-delimited JSON RPC over the child's
+	// stdin/stdout.
+	TransportStdio Transport = "stdio"
+)
+
+// PluginConfig declares a long-lived assistant plugin the Manager supervises.
+type PluginConfig struct {
+	Name       string            `json:"name"`        // e.g. "market_research" - used in API paths
+	Transport  Transport         `json:"transport"`   // must be "stdio" - see Transport's doc comment
+	Command    string            `json:"command"`     // e.g. "python3"
+	Args       []string          `json:"args"`        // e.g. ["backend/assistants/runner.py", "--serve"]
+	WorkingDir string            `json:"working_dir"` // relative to project root, "." for project root
+	Env        map[string]string `json:"env"`         // extra environment variables
+}
+
+// Registry holds the declaratively configured assistant plugins.
+// Populated by InitRegistry at startup, analogous to tools.Registry.
+var Registry = map[string]PluginConfig{}
+
+// InitRegistry initializes the plugin registry.
+// This should be called from main.go after loading configuration.
+func InitRegistry() {
+	Registry = map[string]PluginConfig{
+		"market_research": {
+			Name:       "market_research",
+			Transport:  TransportStdio,
+			Command:    "python3",
+			Args:       []string{"backend/assistants/runner.py", "--serve", "--assistant", "market_research"},
+			WorkingDir: ".",
+		},
+		"sales": {
+			Name:       "sales",
+			Transport:  TransportStdio,
+			Command:    "python3",
+			Args:       []string{"backend/assistants/runner.py", "--serve", "--assistant", "sales"},
+			WorkingDir: ".",
+		},
+		"blog": {
+			Name:       "blog",
+			Transport:  TransportStdio,
+			Command:    "python3",
+			Args:       []string{"backend/assistants/runner.py", "--serve", "--assistant", "blog"},
+			WorkingDir: ".",
+		},
+	}
+}
+
+// Get retrieves a plugin config by name.
+func Get(name string) (PluginConfig, bool) {
+	cfg, ok := Registry[name]
+	return cfg, ok
+}
+
+// List returns all registered plugin configs.
+func List() []PluginConfig {
+	out := make([]PluginConfig, 0, len(Registry))
+	for _, cfg := range Registry {
+		out = append(out, cfg)
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+// Package callbacks delivers HMAC-signed webhook notifications when a
+// task finishes, backed by the task_callbacks table so a delivery that
+// fails (the receiver is down, a timeout, ...) is retried with
+// exponential backoff instead of lost.
+package callbacks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-backend/internal/models"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	maxAttempts     = 8
+	baseBackoff     = 5 * time.Second
+	maxBackoff      = 10 * time.Minute
+	pollInterval    = 10 * time.Second
+	deliveryTimeout = 10 * time.Second
+	batchSize       = 20
+)
+
+// Dispatcher polls task_callbacks for due entries and delivers them.
+type Dispatcher struct {
+	callbackRepo *models.TaskCallbackRepository
+	taskRepo     *models.TaskRepository
+	httpClient   *http.Client
+	logger       hclog.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(callbackRepo *models.TaskCallbackRepository, taskRepo *models.TaskRepository, logger hclog.Logger) *Dispatcher {
+	return &Dispatcher{
+		callbackRepo: callbackRepo,
+		taskRepo:     taskRepo,
+		httpClient:   &http.Client{Timeout: deliveryTimeout},
+		logger:       logger,
+		stop:         make(chan struct{}),
+	}
+}
+
+// Start runs the poll loop in the background, draining due callbacks
+// once per pollInterval.
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				d.drain()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the poll loop to exit and waits for it to finish.
+func (d *Dispatcher) Stop() {
+	close(d.stop)
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) drain() {
+	due, err := d.callbackRepo.FindDue(batchSize)
+	if err != nil {
+		d.logger.Error("failed to load due callbacks", "error", err)
+		return
+	}
+
+	for _, cb := range due {
+		d.deliver(cb)
+	}
+}
+
+// payload is the body POSTed to the callback URL.
+type payload struct {
+	TaskID      string            `json:"task_id"`
+	Status      models.TaskStatus `json:"status"`
+	Output      json.RawMessage   `json:"output,omitempty"`
+	Error       *string           `json:"error,omitempty"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
+
+func (d *Dispatcher) deliver(cb models.TaskCallback) {
+	task, err := d.taskRepo.FindByID(cb.TaskID)
+	if err != nil || task == nil || task.CallbackURL == nil {
+		d.fail(cb, fmt.Errorf("callback task lookup failed: %w", err))
+		return
+	}
+
+	body, err := json.Marshal(payload{
+		TaskID:      task.ID,
+		Status:      task.Status,
+		Output:      task.Output,
+		Error:       task.Error,
+		StartedAt:   task.StartedAt,
+		CompletedAt: task.CompletedAt,
+	})
+	if err != nil {
+		d.fail(cb, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *task.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		d.fail(cb, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if task.CallbackSecret != nil && *task.CallbackSecret != "" {
+		req.Header.Set("X-Signal-Signature", sign(*task.CallbackSecret, body))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		d.fail(cb, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		d.fail(cb, fmt.Errorf("callback endpoint returned %d", resp.StatusCode))
+		return
+	}
+
+	if err := d.callbackRepo.MarkDelivered(cb.ID); err != nil {
+		d.logger.Error("failed to mark callback delivered", "callback_id", cb.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) fail(cb models.TaskCallback, cause error) {
+	attempt := cb.Attempts + 1
+
+	// Once attempts are exhausted, park the row far in the future rather
+	// than adding a distinct terminal state - FindDue skips it forever,
+	// but attempts/last_error stay queryable for operators.
+	next := time.Now().Add(backoff(attempt))
+	if attempt >= maxAttempts {
+		d.logger.Error("callback delivery exhausted retries", "callback_id", cb.ID, "task_id", cb.TaskID, "attempts", attempt, "error", cause)
+		next = time.Now().AddDate(100, 0, 0)
+	} else {
+		d.logger.Warn("callback delivery failed, will retry", "callback_id", cb.ID, "task_id", cb.TaskID, "attempt", attempt, "error", cause)
+	}
+
+	if err := d.callbackRepo.MarkFailedAttempt(cb.ID, cause.Error(), next); err != nil {
+		d.logger.Error("failed to record callback attempt", "callback_id", cb.ID, "error", err)
+	}
+}
+
+// backoff computes an exponential delay capped at maxBackoff - attempt 1
+// waits baseBackoff, attempt 2 waits 2x, and so on.
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
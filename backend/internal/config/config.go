@@ -18,6 +18,9 @@ type Config struct {
 	LogLevel                    string
 	LLMProvider                 string
 	OpenAIAPIKey                string
+	ProxyAllowedHosts           []string
+	DockerSocketPath            string
+	RemoteWorkersJSON           string
 }
 
 var AppConfig *Config
@@ -36,6 +39,9 @@ func Load() *Config {
 		LogLevel:                    getEnv("LOG_LEVEL", "info"),
 		LLMProvider:                 getEnv("LLM_PROVIDER", "openai"),
 		OpenAIAPIKey:                getEnv("OPENAI_API_KEY", ""),
+		ProxyAllowedHosts:           splitNonEmpty(getEnv("PROXY_ALLOWED_HOSTS", "")),
+		DockerSocketPath:            getEnv("DOCKER_SOCKET", ""),
+		RemoteWorkersJSON:           getEnv("REMOTE_WORKERS", ""),
 	}
 
 	AppConfig = config
@@ -57,3 +63,15 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// splitNonEmpty splits a comma-separated list and drops empty entries,
+// so an unset env var produces an empty slice rather than [""].
+func splitNonEmpty(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -2,29 +2,74 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"go-backend/internal/middleware"
 	"go-backend/internal/models"
 	"go-backend/internal/tools"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
 )
 
+// summaryFailureRateSampleSize bounds how many of an agent type's most
+// recent tasks feed the rolling failure rate in Summary.
+const summaryFailureRateSampleSize = 20
+
 // AgentsHandler handles agent-related HTTP requests
 type AgentsHandler struct {
-	agentRepo *models.AgentRepository
-	taskRepo  *models.TaskRepository
-	executor  *tools.Executor
+	agentRepo        *models.AgentRepository
+	taskRepo         *models.TaskRepository
+	toolRegistryRepo *models.ToolRegistryRepository
+	executor         *tools.Executor
+	logger           hclog.Logger
 }
 
 // NewAgentsHandler creates a new agents handler
-func NewAgentsHandler(executor *tools.Executor) *AgentsHandler {
+func NewAgentsHandler(executor *tools.Executor, logger hclog.Logger) *AgentsHandler {
 	return &AgentsHandler{
-		agentRepo: models.NewAgentRepository(),
-		taskRepo:  models.NewTaskRepository(),
-		executor:  executor,
+		agentRepo:        models.NewAgentRepository(),
+		taskRepo:         models.NewTaskRepository(),
+		toolRegistryRepo: models.NewToolRegistryRepository(),
+		executor:         executor,
+		logger:           logger,
+	}
+}
+
+// requestLogger returns the request-scoped logger (request_id/method/path
+// fields already attached by middleware.RequestLogger), falling back to
+// the handler's own logger outside a request.
+func (h *AgentsHandler) requestLogger(c *gin.Context) hclog.Logger {
+	return middleware.LoggerFromContext(c.Request.Context(), h.logger)
+}
+
+// Reload re-reads the tools manifest from disk and diffs the result
+// against the in-memory registry, persisting the new registry so it
+// survives a restart. Lets operators add or change agents without
+// recompiling or restarting the server.
+// POST /api/agents/reload
+func (h *AgentsHandler) Reload(c *gin.Context) {
+	added, removed, err := tools.ReloadRegistry()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if err := h.toolRegistryRepo.SyncAll(tools.ListTools()); err != nil {
+		h.requestLogger(c).Error("failed to persist reloaded tool registry", "error", err)
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"added":   added,
+			"removed": removed,
+		},
+	})
 }
 
 // GetAll returns all agents
@@ -32,7 +77,7 @@ func NewAgentsHandler(executor *tools.Executor) *AgentsHandler {
 func (h *AgentsHandler) GetAll(c *gin.Context) {
 	agents, err := h.agentRepo.FindAll()
 	if err != nil {
-		log.Printf("[AgentsHandler] Error fetching agents: %v", err)
+		h.requestLogger(c).Error("failed to fetch agents", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -53,7 +98,7 @@ func (h *AgentsHandler) GetByID(c *gin.Context) {
 
 	agent, err := h.agentRepo.FindByID(id)
 	if err != nil {
-		log.Printf("[AgentsHandler] Error fetching agent %s: %v", id, err)
+		h.requestLogger(c).Error("failed to fetch agent", "agent_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -77,10 +122,12 @@ func (h *AgentsHandler) GetByID(c *gin.Context) {
 
 // ExecuteRequest is the request body for agent execution
 type ExecuteRequest struct {
-	Action   string         `json:"action" binding:"required"`
-	Input    map[string]any `json:"input" binding:"required"`
-	Priority string         `json:"priority"`
-	UserID   string         `json:"userId"`
+	Action         string         `json:"action" binding:"required"`
+	Input          map[string]any `json:"input" binding:"required"`
+	Priority       string         `json:"priority"`
+	UserID         string         `json:"userId"`
+	CallbackURL    string         `json:"callback_url"`
+	CallbackSecret string         `json:"callback_secret"`
 }
 
 // Execute runs an agent with the given task
@@ -92,7 +139,7 @@ func (h *AgentsHandler) Execute(c *gin.Context) {
 	// Find agent by type (name)
 	agent, err := h.agentRepo.FindByType(name)
 	if err != nil {
-		log.Printf("[AgentsHandler] Error fetching agent %s: %v", name, err)
+		h.requestLogger(c).Error("failed to fetch agent", "agent_type", name, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -145,7 +192,7 @@ func (h *AgentsHandler) Execute(c *gin.Context) {
 	// Create task in database
 	task, err := h.taskRepo.Create(agent.ID, req.Action, req.Input, req.Priority, req.UserID)
 	if err != nil {
-		log.Printf("[AgentsHandler] Error creating task: %v", err)
+		h.requestLogger(c).Error("failed to create task", "agent_id", agent.ID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -153,6 +200,14 @@ func (h *AgentsHandler) Execute(c *gin.Context) {
 		return
 	}
 
+	if req.CallbackURL != "" {
+		if err := h.taskRepo.SetCallback(task.ID, req.CallbackURL, req.CallbackSecret, true); err != nil {
+			h.requestLogger(c).Error("failed to register callback", "task_id", task.ID, "error", err)
+		}
+	}
+
+	taskLogger := h.requestLogger(c).With("task_id", task.ID)
+
 	// Execute tool asynchronously
 	go func() {
 		// Update status to processing
@@ -170,12 +225,12 @@ func (h *AgentsHandler) Execute(c *gin.Context) {
 		for k, v := range req.Input {
 			cliInput[k] = v
 		}
-		log.Printf("[AgentsHandler] Executing task %s with input keys: %+v", task.ID, cliInput)
+		taskLogger.Info("executing task", "action", req.Action)
 
 		// Execute the tool
-		result, err := h.executor.Execute(ctx, tool, cliInput)
+		result, err := h.executor.Execute(ctx, task.ID, tool, cliInput)
 		if err != nil {
-			log.Printf("[AgentsHandler] Tool execution error for task %s: %v", task.ID, err)
+			taskLogger.Error("tool execution failed", "error", err)
 			h.taskRepo.UpdateFailed(task.ID, err.Error())
 			return
 		}
@@ -196,7 +251,10 @@ func (h *AgentsHandler) Execute(c *gin.Context) {
 	})
 }
 
-// Update updates an agent
+// Update updates an agent. Callers can opt into optimistic concurrency by
+// sending the agent's last-known updated_at, either as the If-Match
+// header or as an expected_updated_at body field (RFC3339); the update is
+// then rejected with 409 Conflict if the row has since changed.
 // PUT /api/agents/:id
 func (h *AgentsHandler) Update(c *gin.Context) {
 	id := c.Param("id")
@@ -210,9 +268,26 @@ func (h *AgentsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	agent, err := h.agentRepo.Update(id, updates)
+	expectedUpdatedAt, err := expectedUpdatedAtFromRequest(c, updates)
 	if err != nil {
-		log.Printf("[AgentsHandler] Error updating agent %s: %v", id, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid If-Match/expected_updated_at value: " + err.Error(),
+		})
+		return
+	}
+	delete(updates, "expected_updated_at")
+
+	agent, err := h.agentRepo.Update(id, updates, expectedUpdatedAt)
+	if err != nil {
+		if errors.Is(err, models.ErrAgentUpdateConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		h.requestLogger(c).Error("failed to update agent", "agent_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -234,6 +309,68 @@ func (h *AgentsHandler) Update(c *gin.Context) {
 	})
 }
 
+// expectedUpdatedAtFromRequest reads the caller's expected updated_at from
+// the If-Match header (quotes trimmed, as with a weak ETag) or else the
+// body's expected_updated_at field, parsed as RFC3339. Returns nil, nil
+// when neither is present.
+func expectedUpdatedAtFromRequest(c *gin.Context, body map[string]any) (*time.Time, error) {
+	raw := strings.Trim(c.GetHeader("If-Match"), `"`)
+	if raw == "" {
+		if v, ok := body["expected_updated_at"].(string); ok {
+			raw = v
+		}
+	}
+	if raw == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// Summary returns per-type agent status counts, the most recent status
+// change, and a rolling failure rate over each type's recent tasks.
+// GET /api/agents/summary?window=24h
+func (h *AgentsHandler) Summary(c *gin.Context) {
+	window := parseWindow(c, 24*time.Hour)
+	since := time.Now().Add(-window)
+
+	summary, err := h.agentRepo.Summary(since, summaryFailureRateSampleSize)
+	if err != nil {
+		h.requestLogger(c).Error("failed to fetch summary", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+		"window":  window.String(),
+	})
+}
+
+// parseWindow parses the ?window= query param (a Go duration string, e.g.
+// "24h") into a time.Duration, falling back to defaultWindow when absent
+// or malformed.
+func parseWindow(c *gin.Context, defaultWindow time.Duration) time.Duration {
+	raw := c.Query("window")
+	if raw == "" {
+		return defaultWindow
+	}
+
+	window, err := time.ParseDuration(raw)
+	if err != nil {
+		return defaultWindow
+	}
+	return window
+}
+
 // GetMetrics returns metrics for an agent
 // GET /api/agents/:id/metrics
 func (h *AgentsHandler) GetMetrics(c *gin.Context) {
@@ -242,7 +379,7 @@ func (h *AgentsHandler) GetMetrics(c *gin.Context) {
 	// Verify agent exists
 	agent, err := h.agentRepo.FindByID(id)
 	if err != nil {
-		log.Printf("[AgentsHandler] Error fetching agent %s: %v", id, err)
+		h.requestLogger(c).Error("failed to fetch agent", "agent_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -261,7 +398,7 @@ func (h *AgentsHandler) GetMetrics(c *gin.Context) {
 	// Get status counts
 	statusCounts, err := h.taskRepo.GetStatusCounts(id)
 	if err != nil {
-		log.Printf("[AgentsHandler] Error fetching status counts: %v", err)
+		h.requestLogger(c).Error("failed to fetch status counts", "agent_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -270,12 +407,12 @@ func (h *AgentsHandler) GetMetrics(c *gin.Context) {
 	}
 
 	// Get recent tasks
-	recentTasks, _, err := h.taskRepo.FindAll(map[string]any{
+	recentTasks, _, _, err := h.taskRepo.FindAll(map[string]any{
 		"agentId": id,
 		"limit":   10,
 	})
 	if err != nil {
-		log.Printf("[AgentsHandler] Error fetching recent tasks: %v", err)
+		h.requestLogger(c).Error("failed to fetch recent tasks", "agent_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -298,3 +435,67 @@ func (h *AgentsHandler) GetMetrics(c *gin.Context) {
 		},
 	})
 }
+
+// Stream subscribes to a running task's live stderr lines and
+// terminal status over SSE, replaying whatever backlog the task's
+// broadcaster still has buffered so a client that connects after the
+// run has started doesn't miss earlier output. The stream ends on its
+// own once the terminal "status" event is sent, or immediately if the
+// client disconnects first.
+// GET /api/agents/tasks/:id/stream
+func (h *AgentsHandler) Stream(c *gin.Context) {
+	taskID := c.Param("id")
+
+	task, err := h.taskRepo.FindByID(taskID)
+	if err != nil {
+		h.requestLogger(c).Error("failed to fetch task", "task_id", taskID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Task not found"})
+		return
+	}
+	if task.Status != models.TaskStatusPending && task.Status != models.TaskStatusProcessing {
+		// The executor never registers a broadcaster for a task that
+		// isn't running, so subscribing here would just create and
+		// leak one that nothing will ever clean up.
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "task is " + string(task.Status) + ", not running"})
+		return
+	}
+
+	events, backlog, unsubscribe := h.executor.Subscribe(taskID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, canFlush := c.Writer.(http.Flusher)
+	writeEvent := func(evt tools.BroadcastEvent) {
+		fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", evt.Event, mustMarshal(gin.H{"data": evt.Data}))
+		if canFlush {
+			flusher.Flush()
+		}
+	}
+
+	for _, evt := range backlog {
+		writeEvent(evt)
+	}
+
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(evt)
+			if evt.Event == "status" {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
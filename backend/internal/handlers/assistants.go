@@ -1,32 +1,41 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"time"
 
+	"go-backend/internal/assistants"
+	"go-backend/internal/middleware"
+
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
 )
 
 // AssistantsHandler handles assistant-related HTTP requests
 type AssistantsHandler struct {
-	projectRoot string
+	manager *assistants.Manager
+	logger  hclog.Logger
 }
 
-// NewAssistantsHandler creates a new assistants handler
-func NewAssistantsHandler(projectRoot string) *AssistantsHandler {
+// NewAssistantsHandler creates a new assistants handler backed by a
+// persistent assistant plugin Manager.
+func NewAssistantsHandler(manager *assistants.Manager, logger hclog.Logger) *AssistantsHandler {
 	return &AssistantsHandler{
-		projectRoot: projectRoot,
+		manager: manager,
+		logger:  logger,
 	}
 }
 
+// requestLogger returns the request-scoped logger (request_id/method/path
+// fields already attached by middleware.RequestLogger), falling back to
+// the handler's own logger outside a request.
+func (h *AssistantsHandler) requestLogger(c *gin.Context) hclog.Logger {
+	return middleware.LoggerFromContext(c.Request.Context(), h.logger)
+}
+
 // ChatRequest is the request body for assistant chat
 type ChatRequest struct {
 	Message       string `json:"message" binding:"required"`
@@ -37,21 +46,30 @@ type ChatRequest struct {
 // ChatResponse is the response from assistant chat
 // This contract is LOCKED - do not change without frontend coordination
 type ChatResponse struct {
-	Assistant string      `json:"assistant"`  // Required: assistant name
-	Answer    string      `json:"answer"`     // Required: markdown-formatted answer
-	Citations []string    `json:"citations"`  // Required: array of public URLs (empty if none)
+	Assistant string       `json:"assistant"` // Required: assistant name
+	Answer    string       `json:"answer"`    // Required: markdown-formatted answer
+	Citations []string     `json:"citations"` // Required: array of public URLs (empty if none)
 	Metadata  ChatMetadata `json:"metadata"`  // Required: structured metadata
 }
 
 // ChatMetadata contains structured metadata about the response
 type ChatMetadata struct {
 	Model     string `json:"model"`      // LLM model used
-	Provider  string `json:"provider"`  // LLM provider (ollama, openai, etc.)
-	RagUsed   bool   `json:"rag_used"`  // Whether RAG context was used
-	KB        string `json:"kb"`        // Knowledge base name (empty if no RAG)
+	Provider  string `json:"provider"`   // LLM provider (ollama, openai, etc.)
+	RagUsed   bool   `json:"rag_used"`   // Whether RAG context was used
+	KB        string `json:"kb"`         // Knowledge base name (empty if no RAG)
 	LatencyMs int64  `json:"latency_ms"` // Request latency in milliseconds
 }
 
+// List returns the discoverable set of registered assistant plugins
+// GET /api/assistants
+func (h *AssistantsHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.manager.Discover(),
+	})
+}
+
 // Chat handles assistant chat requests
 // POST /api/assistants/:name/chat
 func (h *AssistantsHandler) Chat(c *gin.Context) {
@@ -80,156 +98,226 @@ func (h *AssistantsHandler) Chat(c *gin.Context) {
 	// Record start time for latency measurement
 	startTime := time.Now()
 
-	log.Printf("[AssistantsHandler] Chat request - Assistant: %s, KB: %s", req.Assistant, req.KnowledgeBase)
+	logger := h.requestLogger(c)
+	logger.Info("chat request received", "assistant", req.Assistant, "kb", req.KnowledgeBase)
 
-	// Prepare input for Python runner
-	input := map[string]interface{}{
+	// Create context with timeout (5 minutes for LLM calls)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	frame, err := h.manager.Invoke(ctx, req.Assistant, map[string]interface{}{
 		"message":        req.Message,
-		"assistant":      req.Assistant,
 		"knowledge_base": req.KnowledgeBase,
+	})
+	if err != nil {
+		logger.Error("plugin invoke failed", "assistant", req.Assistant, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   fmt.Sprintf("Assistant execution failed: %v", err),
+		})
+		return
 	}
 
-	inputJSON, err := json.Marshal(input)
-	if err != nil {
-		log.Printf("[AssistantsHandler] Error marshaling input: %v", err)
+	if frame.Answer == "" {
+		logger.Warn("assistant returned empty answer", "assistant", req.Assistant)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Failed to prepare request",
+			"error":   "Assistant returned empty answer",
 		})
 		return
 	}
 
-	// Create context with timeout (5 minutes for LLM calls)
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
-	defer cancel()
+	// Ensure citations is always an array (never null)
+	citations := frame.Citations
+	if citations == nil {
+		citations = []string{}
+	}
 
-	// Build Python command
-	runnerPath := filepath.Join(h.projectRoot, "backend", "assistants", "runner.py")
-	cmd := exec.CommandContext(ctx, "python3", runnerPath, "--input", string(inputJSON))
+	// Calculate latency
+	latencyMs := time.Since(startTime).Milliseconds()
 
-	// Set working directory
-	cmd.Dir = filepath.Join(h.projectRoot, "backend")
+	// Build final response with locked contract
+	response := ChatResponse{
+		Assistant: req.Assistant,
+		Answer:    frame.Answer,
+		Citations: citations,
+		Metadata: ChatMetadata{
+			Model:     getStringFromMap(frame.Metadata, "model", ""),
+			Provider:  getStringFromMap(frame.Metadata, "provider", "ollama"),
+			RagUsed:   getBoolFromMap(frame.Metadata, "rag_used", false),
+			KB:        req.KnowledgeBase,
+			LatencyMs: latencyMs,
+		},
+	}
 
-	// Set environment variables
-	cmd.Env = append(os.Environ(),
-		"OLLAMA_BASE_URL="+os.Getenv("OLLAMA_BASE_URL"),
-		"PYTHONPATH="+filepath.Join(h.projectRoot, "backend"),
+	// Log observability metrics
+	logger.Info("chat completed",
+		"assistant", response.Assistant,
+		"kb", response.Metadata.KB,
+		"rag_used", response.Metadata.RagUsed,
+		"latency_ms", response.Metadata.LatencyMs,
+		"answer_length", len(response.Answer),
+		"citations", len(response.Citations),
 	)
 
-	// Capture stdout and stderr separately
-	// stdout should contain ONLY JSON, stderr contains logs
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Return successful response
+	c.JSON(http.StatusOK, response)
+}
 
-	// Run command
-	err = cmd.Run()
+// ChatStream handles streaming assistant chat requests over SSE
+// GET/POST /api/assistants/:name/chat/stream
+// With ?follow=true the connection is kept open until the plugin signals
+// completion or the client disconnects, mirroring a tail -f style stream.
+func (h *AssistantsHandler) ChatStream(c *gin.Context) {
+	assistantName := c.Param("name")
+	follow := c.Query("follow") == "true"
 
-	// Log stderr (Python logs) for debugging
-	if stderr.Len() > 0 {
-		log.Printf("[AssistantsHandler] Python runner stderr:\n%s", stderr.String())
+	var req ChatRequest
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+	} else {
+		req.Message = c.Query("message")
+		req.KnowledgeBase = c.Query("knowledge_base")
 	}
 
-	if err != nil {
-		log.Printf("[AssistantsHandler] Python runner error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   fmt.Sprintf("Assistant execution failed: %v", err),
-		})
-		return
+	if req.Assistant == "" {
+		req.Assistant = assistantName
 	}
-
-	// Parse JSON response from Python (stdout only)
-	// First parse as map to handle flexible Python response
-	var rawResponse map[string]interface{}
-	stdoutBytes := stdout.Bytes()
-	if err := json.Unmarshal(stdoutBytes, &rawResponse); err != nil {
-		log.Printf("[AssistantsHandler] Error parsing Python response: %v", err)
-		previewLen := 500
-		if len(stdoutBytes) < previewLen {
-			previewLen = len(stdoutBytes)
-		}
-		log.Printf("[AssistantsHandler] Python stdout (first %d chars): %s", previewLen, string(stdoutBytes[:previewLen]))
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if req.KnowledgeBase == "" {
+		req.KnowledgeBase = req.Assistant
+	}
+	if req.Message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Failed to parse assistant response",
+			"error":   "Missing required field: message",
 		})
 		return
 	}
 
-	// Check for error in metadata
-	rawMetadata, _ := rawResponse["metadata"].(map[string]interface{})
-	if errorMsg, ok := rawMetadata["error"].(string); ok && errorMsg != "" {
-		log.Printf("[AssistantsHandler] Assistant returned error: %s", errorMsg)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   errorMsg,
-		})
-		return
+	startTime := time.Now()
+	logger := h.requestLogger(c)
+	logger.Info("chat stream request received", "assistant", req.Assistant, "kb", req.KnowledgeBase, "follow", follow)
+
+	// A bare chat has the usual 5-minute LLM budget; follow mode has no
+	// deadline of its own and instead relies on the request context being
+	// cancelled when the client disconnects.
+	ctx := c.Request.Context()
+	if !follow {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
 	}
 
-	// Extract and validate required fields
-	answer, _ := rawResponse["answer"].(string)
-	if answer == "" {
-		log.Printf("[AssistantsHandler] Assistant returned empty answer")
+	frames, unsubscribe, err := h.manager.InvokeStream(ctx, req.Assistant, map[string]interface{}{
+		"message":        req.Message,
+		"knowledge_base": req.KnowledgeBase,
+	})
+	if err != nil {
+		logger.Error("plugin invoke failed", "assistant", req.Assistant, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Assistant returned empty answer",
+			"error":   fmt.Sprintf("Assistant execution failed: %v", err),
 		})
 		return
 	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, canFlush := c.Writer.(http.Flusher)
+
+	// Assembled as frames arrive so the terminal "complete" event still
+	// carries the full, locked ChatResponse contract.
+	answer := ""
+	citations := []string{}
+	metadata := map[string]interface{}{}
+
+streamLoop:
+	for {
+		var frame assistants.Frame
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				break streamLoop
+			}
+			frame = f
+		case <-ctx.Done():
+			// Client disconnected (or, without follow, the 5-minute budget
+			// ran out): stop draining frames and let the deferred
+			// unsubscribe release our slot in the plugin's pending map.
+			return
+		}
 
-	assistant, _ := rawResponse["assistant"].(string)
-	if assistant == "" {
-		assistant = req.Assistant
-	}
-
-	// Ensure citations is always an array (never null)
-	var citations []string
-	if rawCitations, ok := rawResponse["citations"].([]interface{}); ok {
-		citations = make([]string, 0, len(rawCitations))
-		for _, cit := range rawCitations {
-			if str, ok := cit.(string); ok {
-				citations = append(citations, str)
+		switch frame.Type {
+		case "token":
+			answer += frame.Text
+		case "citation":
+			citations = append(citations, frame.URL)
+		case "metadata":
+			metadata = frame.Metadata
+		case "done":
+			if frame.Answer != "" {
+				answer = frame.Answer
+			}
+			if frame.Citations != nil {
+				citations = frame.Citations
+			}
+			if frame.Metadata != nil {
+				metadata = frame.Metadata
 			}
+		case "error":
+			logger.Error("assistant stream error", "assistant", req.Assistant, "error", frame.Error)
+			fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", mustMarshal(gin.H{"error": frame.Error}))
+			if canFlush {
+				flusher.Flush()
+			}
+			return
+		}
+
+		payload := mustMarshal(frame)
+		fmt.Fprintf(c.Writer, "data: %s\n\n", payload)
+		if canFlush {
+			flusher.Flush()
 		}
 	}
+
 	if citations == nil {
 		citations = []string{}
 	}
-
-	// Calculate latency
-	latencyMs := time.Since(startTime).Milliseconds()
-
-	// Build normalized metadata
-	normalizedMetadata := ChatMetadata{
-		Model:     getStringFromMap(rawMetadata, "model", ""),
-		Provider:  getStringFromMap(rawMetadata, "provider", "ollama"),
-		RagUsed:   getBoolFromMap(rawMetadata, "rag_used", false),
-		KB:        req.KnowledgeBase,
-		LatencyMs: latencyMs,
-	}
-
-	// Build final response with locked contract
 	response := ChatResponse{
-		Assistant: assistant,
+		Assistant: req.Assistant,
 		Answer:    answer,
 		Citations: citations,
-		Metadata:  normalizedMetadata,
+		Metadata: ChatMetadata{
+			Model:     getStringFromMap(metadata, "model", ""),
+			Provider:  getStringFromMap(metadata, "provider", "ollama"),
+			RagUsed:   getBoolFromMap(metadata, "rag_used", false),
+			KB:        req.KnowledgeBase,
+			LatencyMs: time.Since(startTime).Milliseconds(),
+		},
 	}
 
-	// Log observability metrics
-	log.Printf("[AssistantsHandler] âœ… Assistant: %s, KB: %s, RAG: %v, Latency: %dms, Answer length: %d chars, Citations: %d",
-		response.Assistant,
-		normalizedMetadata.KB,
-		normalizedMetadata.RagUsed,
-		normalizedMetadata.LatencyMs,
-		len(response.Answer),
-		len(response.Citations),
-	)
+	fmt.Fprintf(c.Writer, "event: complete\ndata: %s\n\n", mustMarshal(response))
+	if canFlush {
+		flusher.Flush()
+	}
+}
 
-	// Return successful response
-	c.JSON(http.StatusOK, response)
+func mustMarshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte("{}")
+	}
+	return b
 }
 
 // Helper functions for metadata extraction
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-backend/internal/models"
+	"go-backend/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExecutionsHandler handles execution-related HTTP requests: listing,
+// inspecting, stopping, and manually/event-triggering new ones.
+type ExecutionsHandler struct {
+	executionRepo *models.ExecutionRepository
+	taskRepo      *models.TaskRepository
+	scheduler     *scheduler.Scheduler
+}
+
+// NewExecutionsHandler creates a new executions handler backed by the
+// shared Scheduler, which owns the trigger/fan-out logic.
+func NewExecutionsHandler(executionRepo *models.ExecutionRepository, taskRepo *models.TaskRepository, sched *scheduler.Scheduler) *ExecutionsHandler {
+	return &ExecutionsHandler{
+		executionRepo: executionRepo,
+		taskRepo:      taskRepo,
+		scheduler:     sched,
+	}
+}
+
+// TriggerRequest is the request body for manually or event-triggering an
+// execution.
+type TriggerRequest struct {
+	AgentType string         `json:"agent_type"`
+	Action    string         `json:"action" binding:"required"`
+	Input     map[string]any `json:"input"`
+	Count     int            `json:"count"`
+	UserID    string         `json:"userId"`
+}
+
+// Create manually triggers an execution.
+// POST /api/executions
+func (h *ExecutionsHandler) Create(c *gin.Context) {
+	var req TriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.AgentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Missing required field: agent_type"})
+		return
+	}
+
+	execution, err := h.scheduler.Trigger(c.Request.Context(), models.ExecutionTriggerManual, req.AgentType, req.Action, req.Input, req.Count, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": execution})
+}
+
+// Event triggers an execution from a webhook call, e.g. an upstream
+// system posting that something happened.
+// POST /api/executions/events/:agentType
+func (h *ExecutionsHandler) Event(c *gin.Context) {
+	agentType := c.Param("agentType")
+
+	var req TriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.AgentType == "" {
+		req.AgentType = agentType
+	}
+
+	execution, err := h.scheduler.Trigger(c.Request.Context(), models.ExecutionTriggerEvent, req.AgentType, req.Action, req.Input, req.Count, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": execution})
+}
+
+// List returns recent executions.
+// GET /api/executions
+func (h *ExecutionsHandler) List(c *gin.Context) {
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		offset = o
+	}
+
+	executions, total, err := h.executionRepo.FindAll(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    executions,
+		"total":   total,
+	})
+}
+
+// GetByID returns a single execution along with its child tasks.
+// GET /api/executions/:id
+func (h *ExecutionsHandler) GetByID(c *gin.Context) {
+	id := c.Param("id")
+
+	execution, err := h.executionRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if execution == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Execution not found"})
+		return
+	}
+
+	tasks, _, _, err := h.taskRepo.FindAll(map[string]any{"executionId": id, "limit": 500})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"execution": execution,
+			"tasks":     tasks,
+		},
+	})
+}
+
+// Stop stops a running execution and marks its still-running child tasks
+// stopped.
+// POST /api/executions/:id/stop
+func (h *ExecutionsHandler) Stop(c *gin.Context) {
+	id := c.Param("id")
+
+	execution, err := h.executionRepo.Stop(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if execution == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Execution not found or not running"})
+		return
+	}
+
+	if err := h.taskRepo.StopByExecutionID(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	updated, err := h.executionRepo.RecalculateCounters(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": updated})
+}
@@ -41,6 +41,26 @@ func (h *MccHandler) GetMccs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": mccs})
 }
 
+// AuditSummary returns decision counts by source, the top chosen MCC
+// codes, and per-user override counts from mcc_audit_logs.
+// GET /api/mccs/summary?window=24h
+func (h *MccHandler) AuditSummary(c *gin.Context) {
+	window := parseWindow(c, 24*time.Hour)
+	since := time.Now().Add(-window)
+
+	summary, err := h.repo.AuditSummary(since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+		"window":  window.String(),
+	})
+}
+
 type SaveMccRequest struct {
 	MccCode        string `json:"mcc_code" binding:"required"`
 	OverrideReason string `json:"override_reason"`
@@ -84,15 +104,7 @@ func (h *MccHandler) SaveFinalMcc(c *gin.Context) {
 		return
 	}
 
-	// 3. Update Task Output
-	// We need to merge "final_mcc" into existing output
-	// task.Output is json.RawMessage ([]byte)
-	// We unmarshal to map, update, modify
-
-	// Check if task output is empty or needs init
-	// outputMap parsing omitted for brevity in this step
-
-	// Construct final MCC object
+	// 3. Merge the final MCC decision into the task's output JSON
 	finalMccData := map[string]interface{}{
 		"mcc":             mcc.Code,
 		"selected_by":     req.SelectedBy,
@@ -102,16 +114,13 @@ func (h *MccHandler) SaveFinalMcc(c *gin.Context) {
 		"description":     mcc.Description,
 	}
 
-	// For now, let's just create a new update map passed to repository
-	// But `UpdateCompleted` overwrites output.
-	// We likely need a dedicated `UpdateTaskOutput` or manually merge.
-	// Let's assume we can merge in memory and save.
-
-	// Since `output` is unstructured, we can't easily merge without parsing.
-	// Let's assume we append/merge to a known key "final_mcc_decision"
-
-	// Simplified: Just log the audit now. Output update logic requires careful handling of raw message.
-	// The requirement says: "Update tasks table output JSON (merge final_mcc)"
+	updatedTask, err := h.taskRepo.MergeOutput(taskID, map[string]interface{}{
+		"final_mcc_decision": finalMccData,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save final MCC to task output"})
+		return
+	}
 
 	// 4. Create Audit Log
 	auditLog := models.MccAuditLog{
@@ -131,5 +140,6 @@ func (h *MccHandler) SaveFinalMcc(c *gin.Context) {
 		"success":   true,
 		"message":   "MCC saved successfully",
 		"final_mcc": finalMccData,
+		"task":      updatedTask,
 	})
 }
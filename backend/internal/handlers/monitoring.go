@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"runtime"
@@ -10,28 +13,44 @@ import (
 	"time"
 
 	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/middleware"
 	"go-backend/internal/models"
 	"go-backend/internal/tools"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
 )
 
 // MonitoringHandler handles monitoring-related HTTP requests
 type MonitoringHandler struct {
-	taskRepo  *models.TaskRepository
-	agentRepo *models.AgentRepository
-	executor  *tools.Executor
+	taskRepo          *models.TaskRepository
+	agentRepo         *models.AgentRepository
+	executor          *tools.Executor
+	proxyAllowedHosts []string
+	logger            hclog.Logger
 }
 
-// NewMonitoringHandler creates a new monitoring handler
-func NewMonitoringHandler(executor *tools.Executor) *MonitoringHandler {
+// NewMonitoringHandler creates a new monitoring handler. proxyAllowedHosts
+// is the PROXY_ALLOWED_HOSTS allowlist Proxy checks before the default-deny
+// private-range check; see proxyDeniedCIDRs.
+func NewMonitoringHandler(executor *tools.Executor, proxyAllowedHosts []string, logger hclog.Logger) *MonitoringHandler {
 	return &MonitoringHandler{
-		taskRepo:  models.NewTaskRepository(),
-		agentRepo: models.NewAgentRepository(),
-		executor:  executor,
+		taskRepo:          models.NewTaskRepository(),
+		agentRepo:         models.NewAgentRepository(),
+		executor:          executor,
+		proxyAllowedHosts: proxyAllowedHosts,
+		logger:            logger,
 	}
 }
 
+// requestLogger returns the request-scoped logger (request_id/method/path
+// fields already attached by middleware.RequestLogger), falling back to
+// the handler's own logger outside a request.
+func (h *MonitoringHandler) requestLogger(c *gin.Context) hclog.Logger {
+	return middleware.LoggerFromContext(c.Request.Context(), h.logger)
+}
+
 // Health returns health status of the system
 // GET /api/monitoring/health
 func (h *MonitoringHandler) Health(c *gin.Context) {
@@ -86,7 +105,7 @@ func (h *MonitoringHandler) Metrics(c *gin.Context) {
 	}
 
 	// Get recent activity
-	recentTasks, _, err := h.taskRepo.FindAll(map[string]any{
+	recentTasks, _, _, err := h.taskRepo.FindAll(map[string]any{
 		"limit": 20,
 	})
 	if err != nil {
@@ -146,7 +165,7 @@ func (h *MonitoringHandler) Activity(c *gin.Context) {
 		}
 	}
 
-	tasks, _, err := h.taskRepo.FindAll(map[string]any{
+	tasks, _, _, err := h.taskRepo.FindAll(map[string]any{
 		"limit": limit,
 	})
 	if err != nil {
@@ -163,6 +182,40 @@ func (h *MonitoringHandler) Activity(c *gin.Context) {
 	})
 }
 
+// MetricsPrometheus exposes the same counters as Metrics, plus executor
+// concurrency and execution duration/outcomes, in the Prometheus text
+// exposition format so standard scrapers can consume it without a
+// bespoke parser.
+// GET /api/monitoring/metrics/prometheus
+func (h *MonitoringHandler) MetricsPrometheus(c *gin.Context) {
+	h.executor.RefreshConcurrencyMetrics()
+
+	agents, err := h.agentRepo.FindAll()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "# failed to load agent statuses: %s\n", err.Error())
+		return
+	}
+	agentStatusCounts := map[models.AgentStatus]int{}
+	for _, agent := range agents {
+		agentStatusCounts[agent.Status]++
+	}
+	for status, count := range agentStatusCounts {
+		metrics.AgentStatus.Set(float64(count), string(status))
+	}
+
+	taskStatusCounts, err := h.taskRepo.GetStatusCounts("")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "# failed to load task statuses: %s\n", err.Error())
+		return
+	}
+	for status, count := range taskStatusCounts {
+		metrics.TaskStatus.Set(float64(count), status)
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.String(http.StatusOK, metrics.Default.Render())
+}
+
 // System returns system information
 // GET /api/monitoring/system
 func (h *MonitoringHandler) System(c *gin.Context) {
@@ -188,14 +241,121 @@ func (h *MonitoringHandler) System(c *gin.Context) {
 	})
 }
 
-// Proxy fetches external URLs for iframe preview
+// proxyDeniedCIDRs are the private/link-local/loopback ranges Proxy
+// refuses to fetch unless the target host is explicitly named in
+// PROXY_ALLOWED_HOSTS. 169.254.0.0/16 covers cloud metadata endpoints,
+// the most common SSRF target.
+var proxyDeniedCIDRs = mustParseCIDRs(
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"127.0.0.0/8", "169.254.0.0/16",
+	"::1/128", "fc00::/7",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// proxyMaxResponseBytes caps how much of a proxied response Proxy will
+// buffer into memory.
+const proxyMaxResponseBytes = 10 << 20 // 10 MiB
+
+// resolveProxyHost resolves host to a single IP and validates it,
+// returning the resolved IP so the caller can dial that address
+// directly - fetching the IP that was actually checked rather than
+// letting a second DNS lookup return a different one (DNS rebinding)
+// between validation and the request. A host named in
+// proxyAllowedHosts is trusted outright. When proxyAllowedHosts is
+// non-empty, every other host is rejected; when it is empty, the
+// resolved IP must fall outside proxyDeniedCIDRs.
+func (h *MonitoringHandler) resolveProxyHost(ctx context.Context, host string) (net.IP, error) {
+	for _, allowed := range h.proxyAllowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return lookupFirstIP(ctx, host)
+		}
+	}
+	if len(h.proxyAllowedHosts) > 0 {
+		return nil, fmt.Errorf("host %q is not in PROXY_ALLOWED_HOSTS", host)
+	}
+
+	ip, err := lookupFirstIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, denied := range proxyDeniedCIDRs {
+		if denied.Contains(ip) {
+			return nil, fmt.Errorf("host %q resolves to a disallowed address (%s)", host, ip)
+		}
+	}
+	return ip, nil
+}
+
+func lookupFirstIP(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve host %q: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("could not resolve host %q", host)
+	}
+	return ips[0], nil
+}
+
+// proxyClient builds an http.Client whose Transport resolves and
+// validates the host of every dial - the initial request and every
+// redirect hop alike - against resolveProxyHost, and connects to the
+// resolved IP directly.
+func (h *MonitoringHandler) proxyClient() *http.Client {
+	return &http.Client{
+		Timeout: 15 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				ip, err := h.resolveProxyHost(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				dialer := &net.Dialer{Timeout: 10 * time.Second}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// Proxy fetches external URLs for iframe preview, guarding against SSRF
+// by resolving and validating the target host (and every redirect hop)
+// against PROXY_ALLOWED_HOSTS / proxyDeniedCIDRs, and against unbounded
+// response bodies with a hard size cap.
 // GET /api/monitoring/proxy?url=https://example.com
 func (h *MonitoringHandler) Proxy(c *gin.Context) {
 	targetURL := c.Query("url")
 	if targetURL == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Missing 'url' query parameter",
+			"error":   "missing_url",
+			"message": "Missing 'url' query parameter",
 		})
 		return
 	}
@@ -205,23 +365,37 @@ func (h *MonitoringHandler) Proxy(c *gin.Context) {
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Invalid URL: " + err.Error(),
+			"error":   "invalid_url",
+			"message": "Invalid URL: " + err.Error(),
 		})
 		return
 	}
-	baseURL := parsedURL.Scheme + "://" + parsedURL.Host
-
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 15 * time.Second,
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "unsupported_scheme",
+			"message": "Only http and https URLs are supported",
+		})
+		return
 	}
+	if _, err := h.resolveProxyHost(c.Request.Context(), parsedURL.Hostname()); err != nil {
+		h.requestLogger(c).Warn("proxy host denied", "host", parsedURL.Hostname(), "error", err)
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "host_denied",
+			"message": err.Error(),
+		})
+		return
+	}
+	baseURL := parsedURL.Scheme + "://" + parsedURL.Host
 
 	// Create request with browser-like headers
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "Invalid URL: " + err.Error(),
+			"error":   "invalid_url",
+			"message": "Invalid URL: " + err.Error(),
 		})
 		return
 	}
@@ -232,22 +406,33 @@ func (h *MonitoringHandler) Proxy(c *gin.Context) {
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 
 	// Fetch the URL
-	resp, err := client.Do(req)
+	resp, err := h.proxyClient().Do(req)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{
 			"success": false,
-			"error":   "Failed to fetch URL: " + err.Error(),
+			"error":   "fetch_failed",
+			"message": "Failed to fetch URL: " + err.Error(),
 		})
 		return
 	}
 	defer resp.Body.Close()
 
-	// Read body
-	body, err := io.ReadAll(resp.Body)
+	// Read body, capped at proxyMaxResponseBytes
+	limited := io.LimitReader(resp.Body, proxyMaxResponseBytes+1)
+	body, err := io.ReadAll(limited)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Failed to read response: " + err.Error(),
+			"error":   "read_failed",
+			"message": "Failed to read response: " + err.Error(),
+		})
+		return
+	}
+	if len(body) > proxyMaxResponseBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"error":   "response_too_large",
+			"message": fmt.Sprintf("response exceeded the %d byte limit", proxyMaxResponseBytes),
 		})
 		return
 	}
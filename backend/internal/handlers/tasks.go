@@ -1,28 +1,53 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
+	"go-backend/internal/middleware"
 	"go-backend/internal/models"
+	"go-backend/internal/tools"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
 )
 
 // TasksHandler handles task-related HTTP requests
 type TasksHandler struct {
-	taskRepo *models.TaskRepository
+	taskRepo      *models.TaskRepository
+	callbackRepo  *models.TaskCallbackRepository
+	agentRepo     *models.AgentRepository
+	executionRepo *models.ExecutionRepository
+	executor      *tools.Executor
+	logger        hclog.Logger
 }
 
 // NewTasksHandler creates a new tasks handler
-func NewTasksHandler() *TasksHandler {
+func NewTasksHandler(executor *tools.Executor, logger hclog.Logger) *TasksHandler {
 	return &TasksHandler{
-		taskRepo: models.NewTaskRepository(),
+		taskRepo:      models.NewTaskRepository(),
+		callbackRepo:  models.NewTaskCallbackRepository(),
+		agentRepo:     models.NewAgentRepository(),
+		executionRepo: models.NewExecutionRepository(),
+		executor:      executor,
+		logger:        logger,
 	}
 }
 
-// GetAll returns all tasks with optional filters
+// requestLogger returns the request-scoped logger (request_id/method/path
+// fields already attached by middleware.RequestLogger), falling back to
+// the handler's own logger outside a request.
+func (h *TasksHandler) requestLogger(c *gin.Context) hclog.Logger {
+	return middleware.LoggerFromContext(c.Request.Context(), h.logger)
+}
+
+// GetAll returns tasks matching the given filters, paginated either by
+// limit/offset (the default) or, when ?cursor= is given, by keyset -
+// pass the previous page's next_cursor to fetch the next one. ?cursor=
+// takes priority over ?offset= if both are given.
 // GET /api/tasks
 func (h *TasksHandler) GetAll(c *gin.Context) {
 	filters := make(map[string]any)
@@ -30,12 +55,31 @@ func (h *TasksHandler) GetAll(c *gin.Context) {
 	if agentID := c.Query("agentId"); agentID != "" {
 		filters["agentId"] = agentID
 	}
+	if executionID := c.Query("executionId"); executionID != "" {
+		filters["executionId"] = executionID
+	}
 	if status := c.Query("status"); status != "" {
 		filters["status"] = status
 	}
 	if userID := c.Query("userId"); userID != "" {
 		filters["userId"] = userID
 	}
+	if priority := c.Query("priority"); priority != "" {
+		filters["priority"] = priority
+	}
+	if createdAfter := c.Query("createdAfter"); createdAfter != "" {
+		if t, err := time.Parse(time.RFC3339, createdAfter); err == nil {
+			filters["createdAfter"] = t
+		}
+	}
+	if createdBefore := c.Query("createdBefore"); createdBefore != "" {
+		if t, err := time.Parse(time.RFC3339, createdBefore); err == nil {
+			filters["createdBefore"] = t
+		}
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		filters["cursor"] = cursor
+	}
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
 			filters["limit"] = limit
@@ -47,9 +91,9 @@ func (h *TasksHandler) GetAll(c *gin.Context) {
 		}
 	}
 
-	tasks, total, err := h.taskRepo.FindAll(filters)
+	tasks, total, nextCursor, err := h.taskRepo.FindAll(filters)
 	if err != nil {
-		log.Printf("[TasksHandler] Error fetching tasks: %v", err)
+		h.requestLogger(c).Error("failed to fetch tasks", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -58,9 +102,10 @@ func (h *TasksHandler) GetAll(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    tasks,
-		"total":   total,
+		"success":     true,
+		"data":        tasks,
+		"total":       total,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -71,7 +116,7 @@ func (h *TasksHandler) GetByID(c *gin.Context) {
 
 	task, err := h.taskRepo.FindByID(id)
 	if err != nil {
-		log.Printf("[TasksHandler] Error fetching task %s: %v", id, err)
+		h.requestLogger(c).Error("failed to fetch task", "task_id", id, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -93,6 +138,102 @@ func (h *TasksHandler) GetByID(c *gin.Context) {
 	})
 }
 
+// ResumeRequest is the request body for pushing a result back into a
+// pending task.
+type ResumeRequest struct {
+	Status models.TaskStatus `json:"status" binding:"required"`
+	Output map[string]any    `json:"output"`
+	Error  string            `json:"error"`
+}
+
+// Resume lets a caller that handed a task off to external work push the
+// result back in. Safe to retry: the same Idempotency-Key only applies
+// the result once, so a caller that times out waiting for a response can
+// resend without double-delivering.
+// POST /api/tasks/:id/resume
+func (h *TasksHandler) Resume(c *gin.Context) {
+	id := c.Param("id")
+
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Idempotency-Key header is required"})
+		return
+	}
+
+	var req ResumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if req.Status != models.TaskStatusCompleted && req.Status != models.TaskStatusFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "status must be completed or failed"})
+		return
+	}
+
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Task not found"})
+		return
+	}
+
+	if task.Status != models.TaskStatusPending {
+		// Not pending doesn't necessarily mean "conflict" - if this exact
+		// key already resumed the task, this is a retry of that success
+		// arriving after the task moved on, and it should replay the same
+		// 200 rather than claim a fresh key (or worse, a 409) against a
+		// request that already succeeded.
+		claimedTaskID, found, err := h.callbackRepo.ResumeKeyTaskID(idempotencyKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		if found && claimedTaskID == id {
+			c.JSON(http.StatusOK, gin.H{"success": true, "data": task, "message": "Already resumed for this Idempotency-Key"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "task is " + string(task.Status) + ", not pending"})
+		return
+	}
+
+	var ok bool
+	if req.Status == models.TaskStatusCompleted {
+		ok, err = h.taskRepo.UpdateCompleted(id, req.Output)
+	} else {
+		ok, err = h.taskRepo.UpdateFailed(id, req.Error)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if !ok {
+		// Lost a race with a concurrent resume/stop between the status
+		// check above and the update - nothing was applied, so there's
+		// nothing to claim the key against either.
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "task is no longer pending"})
+		return
+	}
+
+	// Only claim the key once the result has actually been applied, so a
+	// request that never gets this far (because the task wasn't pending)
+	// never burns it - see the status check above.
+	if _, err := h.callbackRepo.ClaimResume(idempotencyKey, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	updated, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": updated})
+}
+
 // GetStatusCounts returns task counts by status
 // GET /api/tasks/status/counts
 func (h *TasksHandler) GetStatusCounts(c *gin.Context) {
@@ -100,7 +241,7 @@ func (h *TasksHandler) GetStatusCounts(c *gin.Context) {
 
 	counts, err := h.taskRepo.GetStatusCounts(agentID)
 	if err != nil {
-		log.Printf("[TasksHandler] Error fetching status counts: %v", err)
+		h.requestLogger(c).Error("failed to fetch status counts", "agent_id", agentID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"error":   err.Error(),
@@ -113,3 +254,110 @@ func (h *TasksHandler) GetStatusCounts(c *gin.Context) {
 		"data":    counts,
 	})
 }
+
+// Retry creates a new child task for the same execution as a failed
+// task, reusing its original action/input, and runs it through the
+// executor the same way AgentsHandler.Execute does. Lets a caller
+// recover from a transient failure (including one the lease supervisor
+// raised after a stale heartbeat) without resubmitting the original
+// request from scratch.
+// POST /api/tasks/:id/retry
+func (h *TasksHandler) Retry(c *gin.Context) {
+	id := c.Param("id")
+
+	task, err := h.taskRepo.FindByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Task not found"})
+		return
+	}
+	if task.Status != models.TaskStatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": "task is " + string(task.Status) + ", not failed"})
+		return
+	}
+
+	agent, err := h.agentRepo.FindByID(task.AgentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if agent == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "Agent not found: " + task.AgentID})
+		return
+	}
+
+	tool, exists := tools.GetToolByAgentType(agent.Type)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "No CLI tool configured for agent type: " + agent.Type})
+		return
+	}
+
+	var input map[string]any
+	if err := json.Unmarshal(task.Input, &input); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	executionID := ""
+	if task.ExecutionID != nil {
+		executionID = *task.ExecutionID
+	}
+	userID := ""
+	if task.UserID != nil {
+		userID = *task.UserID
+	}
+
+	retryTask, err := h.taskRepo.CreateWithExecution(task.AgentID, task.Action, input, string(task.Priority), userID, executionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	if executionID != "" {
+		h.executionRepo.RecalculateCounters(executionID)
+	}
+
+	taskLogger := h.requestLogger(c).With("task_id", retryTask.ID, "retry_of", id)
+
+	go func() {
+		h.taskRepo.UpdateStatus(retryTask.ID, models.TaskStatusProcessing)
+		if executionID != "" {
+			h.executionRepo.RecalculateCounters(executionID)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), tool.Timeout)
+		defer cancel()
+
+		cliInput := map[string]any{
+			"action":  retryTask.Action,
+			"task_id": retryTask.ID,
+		}
+		for k, v := range input {
+			cliInput[k] = v
+		}
+
+		result, err := h.executor.Execute(ctx, retryTask.ID, tool, cliInput)
+		switch {
+		case err != nil:
+			taskLogger.Error("retry execution failed", "error", err)
+			h.taskRepo.UpdateFailed(retryTask.ID, err.Error())
+		case result.Status == "failed":
+			taskLogger.Warn("retry completed with failed status", "error", result.Error)
+			h.taskRepo.UpdateFailed(retryTask.ID, result.Error)
+		default:
+			h.taskRepo.UpdateCompleted(retryTask.ID, result.Output)
+		}
+
+		if executionID != "" {
+			h.executionRepo.RecalculateCounters(executionID)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    retryTask,
+		"message": "Retry task enqueued successfully",
+	})
+}
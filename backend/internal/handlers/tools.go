@@ -6,14 +6,18 @@ import (
 	"go-backend/internal/tools"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
 )
 
 // ToolsHandler handles tool-related HTTP requests
-type ToolsHandler struct{}
+type ToolsHandler struct {
+	executor *tools.Executor
+	logger   hclog.Logger
+}
 
 // NewToolsHandler creates a new tools handler
-func NewToolsHandler() *ToolsHandler {
-	return &ToolsHandler{}
+func NewToolsHandler(executor *tools.Executor, logger hclog.Logger) *ToolsHandler {
+	return &ToolsHandler{executor: executor, logger: logger}
 }
 
 // ListTools returns all available tools
@@ -69,3 +73,14 @@ func (h *ToolsHandler) GetTool(c *gin.Context) {
 		},
 	})
 }
+
+// Stats returns the executor's current queued/in-flight/rejected job
+// counters and per-tool/global semaphore usage, for dashboards and
+// Prometheus scraping.
+// GET /api/executor/stats
+func (h *ToolsHandler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.executor.GetStats(),
+	})
+}
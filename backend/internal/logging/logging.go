@@ -0,0 +1,37 @@
+// Package logging builds the process-wide structured logger used across
+// handlers, models, and the tool executor, replacing ad-hoc log.Printf
+// calls with leveled, field-based logging.
+package logging
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// New constructs the root logger. Level and format are controlled by the
+// LOG_LEVEL (trace|debug|info|warn|error, default "info") and LOG_JSON
+// ("true" for machine-readable JSON, default pretty/human-readable) env
+// vars, so production can ship JSON to a log aggregator while local dev
+// stays readable.
+func New() hclog.Logger {
+	level := hclog.LevelFromString(getEnv("LOG_LEVEL", "info"))
+	if level == hclog.NoLevel {
+		level = hclog.Info
+	}
+
+	return hclog.New(&hclog.LoggerOptions{
+		Name:       "agentx",
+		Level:      level,
+		JSONFormat: strings.EqualFold(getEnv("LOG_JSON", "false"), "true"),
+		Output:     os.Stdout,
+	})
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
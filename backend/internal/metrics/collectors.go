@@ -0,0 +1,51 @@
+package metrics
+
+// Default is the process-wide registry scraped by
+// GET /api/monitoring/metrics/prometheus.
+var Default = NewRegistry()
+
+// executionDurationBuckets covers a quick tool call up through one that
+// brushes against a multi-minute timeout.
+var executionDurationBuckets = []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120, 300}
+
+var (
+	// ToolConcurrency reports each tool's current semaphore usage, so
+	// "used" and "capacity" for the same tool plot as two series.
+	ToolConcurrency = Default.MustRegisterGauge(NewGaugeVec(
+		"agentx_tool_concurrency",
+		"Current per-tool concurrency slots, by used/capacity.",
+		"tool", "kind",
+	))
+
+	// TaskOutcomes counts finished tool executions as they happen, keyed
+	// by agent type and outcome, so it doesn't have to be re-derived from
+	// the tasks table on every scrape.
+	TaskOutcomes = Default.MustRegisterCounter(NewCounterVec(
+		"agentx_task_outcomes_total",
+		"Count of finished tool executions by agent type and outcome.",
+		"agent_type", "outcome",
+	))
+
+	// ExecutionDuration observes how long each tool execution took,
+	// regardless of outcome.
+	ExecutionDuration = Default.MustRegisterHistogram(NewHistogram(
+		"agentx_execution_duration_seconds",
+		"Tool execution duration in seconds.",
+		executionDurationBuckets,
+	))
+
+	// AgentStatus and TaskStatus are set at scrape time from the
+	// agents/tasks tables (see MonitoringHandler.MetricsPrometheus),
+	// since a point-in-time count of current statuses doesn't need to be
+	// tracked incrementally the way outcomes/durations do.
+	AgentStatus = Default.MustRegisterGauge(NewGaugeVec(
+		"agentx_agents_status",
+		"Number of agents currently in each status.",
+		"status",
+	))
+	TaskStatus = Default.MustRegisterGauge(NewGaugeVec(
+		"agentx_tasks_status",
+		"Number of tasks currently in each status.",
+		"status",
+	))
+)
@@ -0,0 +1,239 @@
+// Package metrics renders process metrics in the Prometheus text
+// exposition format for GET /api/monitoring/metrics/prometheus. It
+// implements the small subset of gauge/counter/histogram collectors this
+// service needs by hand rather than taking on the client_golang
+// dependency, in keeping with this codebase's preference for a small
+// local implementation over a new third-party package when the surface
+// needed is narrow (see the hand-rolled cron matcher in
+// internal/scheduler and sqlbuilder in place of squirrel).
+package metrics
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GaugeVec is a named gauge with a fixed set of label names, holding one
+// value per distinct combination of label values (e.g. one per tool for
+// "used" and "capacity").
+type GaugeVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	order  []string
+}
+
+// NewGaugeVec creates a gauge named name, labeled by labels.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+// Set records value for the given label values, which must be supplied
+// in the same order as labels passed to NewGaugeVec.
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.values[key]; !ok {
+		g.order = append(g.order, key)
+	}
+	g.values[key] = value
+}
+
+func (g *GaugeVec) write(sb *strings.Builder) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.values) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, key := range g.order {
+		sb.WriteString(g.name)
+		sb.WriteString(labelString(g.labels, strings.Split(key, "\xff")))
+		fmt.Fprintf(sb, " %s\n", formatFloat(g.values[key]))
+	}
+}
+
+// CounterVec is a named, monotonically-increasing counter with a fixed
+// set of label names (e.g. task outcomes keyed by agent type and
+// status). name should already carry the "_total" suffix.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	order  []string
+}
+
+// NewCounterVec creates a counter named name, labeled by labels.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+// Inc increments the counter for the given label values by 1.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the given label values by delta, which
+// must be non-negative.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.values[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.values[key] += delta
+}
+
+func (c *CounterVec) write(sb *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.values) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range c.order {
+		sb.WriteString(c.name)
+		sb.WriteString(labelString(c.labels, strings.Split(key, "\xff")))
+		fmt.Fprintf(sb, " %s\n", formatFloat(c.values[key]))
+	}
+}
+
+// Histogram tracks the distribution of observed values (e.g. execution
+// duration in seconds) across a fixed set of cumulative buckets.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64 // counts[i] = observations <= buckets[i]; last slot is +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram named name with the given bucket
+// upper bounds, which need not be pre-sorted.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{name: name, help: help, buckets: sorted, counts: make([]uint64, len(sorted)+1)}
+}
+
+// Observe records a single value.
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sum += value
+	h.count++
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.counts[len(h.buckets)]++ // +Inf always matches
+}
+
+func (h *Histogram) write(sb *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, bound := range h.buckets {
+		fmt.Fprintf(sb, "%s_bucket{le=%q} %d\n", h.name, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(sb, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.counts[len(h.buckets)])
+	fmt.Fprintf(sb, "%s_sum %s\n", h.name, formatFloat(h.sum))
+	fmt.Fprintf(sb, "%s_count %d\n", h.name, h.count)
+}
+
+func labelString(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%q", name, values[i])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Registry collects the gauges, counters, and histograms to render on a
+// scrape.
+type Registry struct {
+	gauges     []*GaugeVec
+	counters   []*CounterVec
+	histograms []*Histogram
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// MustRegisterGauge registers g and returns it, so registration and
+// assignment can happen in one line at package init.
+func (r *Registry) MustRegisterGauge(g *GaugeVec) *GaugeVec {
+	r.gauges = append(r.gauges, g)
+	return g
+}
+
+// MustRegisterCounter registers c and returns it.
+func (r *Registry) MustRegisterCounter(c *CounterVec) *CounterVec {
+	r.counters = append(r.counters, c)
+	return c
+}
+
+// MustRegisterHistogram registers h and returns it.
+func (r *Registry) MustRegisterHistogram(h *Histogram) *Histogram {
+	r.histograms = append(r.histograms, h)
+	return h
+}
+
+// Render returns every registered collector, plus Go runtime memory and
+// goroutine gauges, in Prometheus text exposition format.
+func (r *Registry) Render() string {
+	var sb strings.Builder
+
+	for _, g := range r.gauges {
+		g.write(&sb)
+	}
+	for _, c := range r.counters {
+		c.write(&sb)
+	}
+	for _, h := range r.histograms {
+		h.write(&sb)
+	}
+	writeRuntimeMetrics(&sb)
+
+	return sb.String()
+}
+
+func writeRuntimeMetrics(sb *strings.Builder) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	fmt.Fprintf(sb, "# HELP go_memstats_alloc_bytes Bytes of allocated heap objects.\n# TYPE go_memstats_alloc_bytes gauge\ngo_memstats_alloc_bytes %d\n", mem.Alloc)
+	fmt.Fprintf(sb, "# HELP go_memstats_sys_bytes Total bytes of memory obtained from the OS.\n# TYPE go_memstats_sys_bytes gauge\ngo_memstats_sys_bytes %d\n", mem.Sys)
+	fmt.Fprintf(sb, "# HELP go_goroutines Number of goroutines that currently exist.\n# TYPE go_goroutines gauge\ngo_goroutines %d\n", runtime.NumGoroutine())
+}
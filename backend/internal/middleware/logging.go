@@ -1,32 +1,29 @@
 package middleware
 
 import (
-	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/go-hclog"
 )
 
-// LoggingMiddleware logs HTTP requests
-func LoggingMiddleware() gin.HandlerFunc {
+// LoggingMiddleware logs each request's outcome (status, latency) using
+// the request-scoped logger RequestLogger attached to the context, so
+// these entries carry the same request_id/method/path/user_id fields as
+// everything else logged while handling the request. root is the
+// fallback used if RequestLogger wasn't registered ahead of this one.
+func LoggingMiddleware(root hclog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		startTime := time.Now()
-		path := c.Request.URL.Path
+		start := time.Now()
 		query := c.Request.URL.RawQuery
 
-		// Process request
 		c.Next()
 
-		// Calculate latency
-		latency := time.Since(startTime)
-
-		// Log format
-		log.Printf("[%s] %s %s %d %v",
-			c.Request.Method,
-			path,
-			query,
-			c.Writer.Status(),
-			latency,
+		logger := LoggerFromContext(c.Request.Context(), root)
+		logger.Info("request completed",
+			"query", query,
+			"status", c.Writer.Status(),
+			"latency", time.Since(start).String(),
 		)
 	}
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+)
+
+type loggerCtxKey struct{}
+
+// RequestLogger attaches a request-scoped logger carrying request_id,
+// method, path, and (when present) user_id fields to the request
+// context, so downstream code can log with fields correlated back to
+// this specific request. user_id is read from the X-User-ID header,
+// since there's no session/auth middleware yet to derive it from.
+func RequestLogger(root hclog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := uuid.New().String()
+		fields := []any{
+			"request_id", requestID,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+		}
+		if userID := c.GetHeader("X-User-ID"); userID != "" {
+			fields = append(fields, "user_id", userID)
+		}
+		logger := root.With(fields...)
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), loggerCtxKey{}, logger))
+		c.Header("X-Request-ID", requestID)
+
+		c.Next()
+	}
+}
+
+// LoggerFromContext returns the request-scoped logger attached by
+// RequestLogger, or root as a fallback when none is present (e.g. in
+// tests or background goroutines started outside a request).
+func LoggerFromContext(ctx context.Context, root hclog.Logger) hclog.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey{}).(hclog.Logger); ok {
+		return logger
+	}
+	return root
+}
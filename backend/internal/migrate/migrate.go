@@ -0,0 +1,103 @@
+// Package migrate gives operators a single, version-tracked path for
+// schema changes (the agentx-admin "migrate" subcommand), replacing
+// ad-hoc InitXTables() calls sprinkled across main()/one-off scripts
+// with a schema_migrations table of what has actually been applied.
+package migrate
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"go-backend/internal/database"
+	"go-backend/internal/models"
+)
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+var filenamePattern = regexp.MustCompile(`^(\d{4})_(.+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change. Down may be nil when a
+// migration isn't safely reversible.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func() error
+	Down    func() error
+}
+
+// legacyMigrations replay the schema created by the ad-hoc InitXTables
+// functions that predate this package, so a database that has never seen
+// schema_migrations lands on versions 1-5 without error when the schema
+// already exists: those functions are safe to re-run (CREATE ... IF NOT
+// EXISTS / ADD COLUMN IF NOT EXISTS, and InitExecutionTables tolerates
+// its foreign key already existing). They have no down step since
+// dropping tables other code still depends on isn't a safe revert.
+var legacyMigrations = []Migration{
+	{Version: 1, Name: "mcc_tables", Up: models.InitMccTables},
+	{Version: 2, Name: "execution_tables", Up: models.InitExecutionTables},
+	{Version: 3, Name: "scheduled_job_tables", Up: models.InitScheduledJobTables},
+	{Version: 4, Name: "tool_registry_tables", Up: models.InitToolRegistryTables},
+	{Version: 5, Name: "task_callback_tables", Up: models.InitTaskCallbackTables},
+}
+
+// Load returns every migration in version order: the legacy bootstrap
+// steps above, followed by any NNNN_name.up.sql / .down.sql pairs
+// embedded under sql/.
+func Load() ([]Migration, error) {
+	migrations := append([]Migration{}, legacyMigrations...)
+
+	entries, err := sqlFiles.ReadDir("sql")
+	if err != nil {
+		return migrations, nil
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		m := filenamePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		name, direction := m[2], m[3]
+
+		contents, err := sqlFiles.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: reading %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+		}
+
+		switch direction {
+		case "up":
+			mig.Up = execSQL(string(contents))
+		case "down":
+			mig.Down = execSQL(string(contents))
+		}
+	}
+
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func execSQL(sqlText string) func() error {
+	return func() error {
+		_, err := database.DB.Exec(sqlText)
+		return err
+	}
+}
@@ -0,0 +1,154 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+
+	"go-backend/internal/database"
+)
+
+// EnsureSchemaMigrationsTable creates the tracking table used by
+// Up/Down/Status. It doesn't depend on any of the legacy InitXTables
+// functions having run first.
+func EnsureSchemaMigrationsTable() error {
+	_, err := database.DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+func appliedVersions() (map[int]bool, error) {
+	rows, err := database.DB.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every migration not yet recorded in schema_migrations, in
+// version order, returning the "version_name" labels applied.
+func Up() ([]string, error) {
+	if err := EnsureSchemaMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []string
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if m.Up == nil {
+			return ran, fmt.Errorf("migrate: migration %d_%s has no up step", m.Version, m.Name)
+		}
+		if err := m.Up(); err != nil {
+			return ran, fmt.Errorf("migrate: applying %d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := database.DB.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return ran, fmt.Errorf("migrate: recording %d_%s: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, fmt.Sprintf("%d_%s", m.Version, m.Name))
+	}
+
+	return ran, nil
+}
+
+// Down reverts the most recently applied migration, failing if it has no
+// down step. Returns "" if nothing is applied.
+func Down() (string, error) {
+	if err := EnsureSchemaMigrationsTable(); err != nil {
+		return "", err
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return "", err
+	}
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	applied, err := appliedVersions()
+	if err != nil {
+		return "", err
+	}
+	if len(applied) == 0 {
+		return "", nil
+	}
+
+	latest := 0
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+	}
+
+	m, ok := byVersion[latest]
+	if !ok || m.Down == nil {
+		return "", fmt.Errorf("migrate: migration %d_%s has no down step", latest, m.Name)
+	}
+	if err := m.Down(); err != nil {
+		return "", fmt.Errorf("migrate: reverting %d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := database.DB.Exec(`DELETE FROM schema_migrations WHERE version = $1`, latest); err != nil {
+		return "", fmt.Errorf("migrate: unrecording %d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return fmt.Sprintf("%d_%s", m.Version, m.Name), nil
+}
+
+// StatusEntry reports one migration's applied state, for the `status`
+// subcommand.
+type StatusEntry struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+// Status reports every known migration and whether it has been applied.
+func Status() ([]StatusEntry, error) {
+	if err := EnsureSchemaMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StatusEntry, 0, len(migrations))
+	for _, m := range migrations {
+		entries = append(entries, StatusEntry{Version: m.Version, Name: m.Name, Applied: applied[m.Version]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Version < entries[j].Version })
+
+	return entries, nil
+}
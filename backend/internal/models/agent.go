@@ -3,9 +3,11 @@ package models
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"go-backend/internal/database"
+	"go-backend/internal/sqlbuilder"
 )
 
 type AgentStatus string
@@ -33,6 +35,16 @@ func NewAgentRepository() *AgentRepository {
 	return &AgentRepository{}
 }
 
+// AgentTypeSummary is the per-type row returned by Summary: status counts,
+// the most recent status change, and a rolling failure rate computed over
+// the agent's last N tasks within the requested window.
+type AgentTypeSummary struct {
+	Type             string         `json:"type"`
+	StatusCounts     map[string]int `json:"status_counts"`
+	LastStatusChange time.Time      `json:"last_status_change"`
+	FailureRate      float64        `json:"failure_rate"`
+}
+
 func (r *AgentRepository) FindAll() ([]Agent, error) {
 	query := `SELECT id, type, name, description, status, config, created_at, updated_at FROM agents ORDER BY created_at DESC`
 
@@ -119,58 +131,145 @@ func (r *AgentRepository) FindByType(agentType string) (*Agent, error) {
 	return agent, nil
 }
 
-func (r *AgentRepository) Update(id string, updates map[string]any) (*Agent, error) {
-	// Build dynamic update query
-	setClauses := []string{}
-	args := []any{}
-	argNum := 1
+// Summary aggregates agents by type and status, joining in a rolling
+// failure rate computed from each type's last sampleSize tasks created
+// since the given cutoff. Implemented as a single query so dashboards can
+// poll it cheaply instead of reducing FindAll results in memory.
+func (r *AgentRepository) Summary(since time.Time, sampleSize int) ([]AgentTypeSummary, error) {
+	query := `
+		WITH status_counts AS (
+			SELECT type, status, COUNT(*)::int AS cnt, MAX(updated_at) AS last_change
+			FROM agents
+			GROUP BY type, status
+		),
+		recent_tasks AS (
+			SELECT t.status AS task_status, a.type AS agent_type,
+				ROW_NUMBER() OVER (PARTITION BY a.type ORDER BY t.created_at DESC) AS rn
+			FROM tasks t
+			JOIN agents a ON a.id = t.agent_id
+			WHERE t.created_at >= $1
+		),
+		failure_rates AS (
+			SELECT agent_type,
+				COUNT(*) FILTER (WHERE task_status = 'failed')::float8 / NULLIF(COUNT(*), 0) AS failure_rate
+			FROM recent_tasks
+			WHERE rn <= $2
+			GROUP BY agent_type
+		)
+		SELECT sc.type, sc.status, sc.cnt, sc.last_change, COALESCE(fr.failure_rate, 0)
+		FROM status_counts sc
+		LEFT JOIN failure_rates fr ON fr.agent_type = sc.type
+		ORDER BY sc.type, sc.status
+	`
+
+	rows, err := database.DB.Query(query, since, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []AgentTypeSummary{}
+	byType := map[string]int{} // agent type -> index into summaries
+
+	for rows.Next() {
+		var (
+			agentType   string
+			status      string
+			count       int
+			lastChange  time.Time
+			failureRate float64
+		)
+
+		if err := rows.Scan(&agentType, &status, &count, &lastChange, &failureRate); err != nil {
+			return nil, err
+		}
+
+		idx, ok := byType[agentType]
+		if !ok {
+			idx = len(summaries)
+			byType[agentType] = idx
+			summaries = append(summaries, AgentTypeSummary{
+				Type:         agentType,
+				StatusCounts: map[string]int{},
+				FailureRate:  failureRate,
+			})
+		}
+
+		summary := &summaries[idx]
+		summary.StatusCounts[status] = count
+		if lastChange.After(summary.LastStatusChange) {
+			summary.LastStatusChange = lastChange
+		}
+	}
+
+	return summaries, rows.Err()
+}
+
+// ErrAgentUpdateConflict is returned by Update when expectedUpdatedAt is
+// given and doesn't match the row's current updated_at, i.e. the agent was
+// modified concurrently since the caller last read it.
+var ErrAgentUpdateConflict = errors.New("agent was updated concurrently")
+
+// Update applies a partial update built from a client-supplied map. When
+// expectedUpdatedAt is non-nil, the update is conditioned on the row's
+// current updated_at matching it (optimistic concurrency): a mismatch (or
+// an update racing a concurrent writer) yields ErrAgentUpdateConflict
+// rather than silently applying over stale data.
+func (r *AgentRepository) Update(id string, updates map[string]any, expectedUpdatedAt *time.Time) (*Agent, error) {
+	setUpdates := map[string]any{}
 
 	if name, ok := updates["name"].(string); ok {
-		setClauses = append(setClauses, "name = $"+string(rune('0'+argNum)))
-		args = append(args, name)
-		argNum++
+		setUpdates["name"] = name
 	}
 	if description, ok := updates["description"].(string); ok {
-		setClauses = append(setClauses, "description = $"+string(rune('0'+argNum)))
-		args = append(args, description)
-		argNum++
+		setUpdates["description"] = description
 	}
 	if status, ok := updates["status"].(string); ok {
-		setClauses = append(setClauses, "status = $"+string(rune('0'+argNum)))
-		args = append(args, status)
-		argNum++
+		setUpdates["status"] = status
 	}
 	if config, ok := updates["config"]; ok {
-		configJSON, _ := json.Marshal(config)
-		setClauses = append(setClauses, "config = $"+string(rune('0'+argNum)))
-		args = append(args, configJSON)
-		argNum++
+		setUpdates["config"] = config
 	}
 
-	if len(setClauses) == 0 {
+	if len(setUpdates) == 0 {
 		return r.FindByID(id)
 	}
 
-	// Always update updated_at
-	setClauses = append(setClauses, "updated_at = NOW()")
-	args = append(args, id)
+	setUpdates["updated_at"] = time.Now()
 
-	query := "UPDATE agents SET "
-	for i, clause := range setClauses {
-		if i > 0 {
-			query += ", "
-		}
-		query += clause
+	var conditions []sqlbuilder.Condition
+	if expectedUpdatedAt != nil {
+		conditions = append(conditions, sqlbuilder.Condition{Column: "updated_at", Value: *expectedUpdatedAt})
 	}
-	query += " WHERE id = $" + string(rune('0'+argNum)) + " RETURNING id, type, name, description, status, config, created_at, updated_at"
+
+	query, args, err := sqlbuilder.BuildUpdate("agents", setUpdates, "id", id, conditions...)
+	if err != nil {
+		return nil, err
+	}
+	query += " RETURNING id, type, name, description, status, config, created_at, updated_at"
 
 	agent := &Agent{}
 	var config sql.NullString
 
-	err := database.DB.QueryRow(query, args...).Scan(
+	err = database.DB.QueryRow(query, args...).Scan(
 		&agent.ID, &agent.Type, &agent.Name, &agent.Description,
 		&agent.Status, &config, &agent.CreatedAt, &agent.UpdatedAt,
 	)
+	if err == sql.ErrNoRows {
+		if expectedUpdatedAt == nil {
+			return nil, nil
+		}
+		// Zero rows could mean "no such agent" or "updated_at didn't
+		// match" - check which so the caller can tell 404 from 409 apart.
+		existing, findErr := r.FindByID(id)
+		if findErr != nil {
+			return nil, findErr
+		}
+		if existing == nil {
+			return nil, nil
+		}
+		return nil, ErrAgentUpdateConflict
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,325 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"go-backend/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// ExecutionTrigger identifies what caused an execution to be created.
+type ExecutionTrigger string
+
+const (
+	ExecutionTriggerManual    ExecutionTrigger = "manual"
+	ExecutionTriggerScheduled ExecutionTrigger = "scheduled"
+	ExecutionTriggerEvent     ExecutionTrigger = "event"
+)
+
+// ExecutionStatus is the overall state of an execution, derived from the
+// status of its child tasks.
+type ExecutionStatus string
+
+const (
+	ExecutionStatusRunning   ExecutionStatus = "running"
+	ExecutionStatusCompleted ExecutionStatus = "completed"
+	ExecutionStatusStopped   ExecutionStatus = "stopped"
+)
+
+// Execution is a single run of an agent fanned out into one or more child
+// tasks, with counters kept in sync with those tasks' statuses.
+type Execution struct {
+	ID         string           `json:"id"`
+	AgentID    string           `json:"agent_id"`
+	Trigger    ExecutionTrigger `json:"trigger"`
+	Status     ExecutionStatus  `json:"status"`
+	Total      int              `json:"total"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	InProgress int              `json:"in_progress"`
+	Stopped    int              `json:"stopped"`
+	StartedAt  time.Time        `json:"started_at"`
+	EndedAt    *time.Time       `json:"ended_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+type ExecutionRepository struct{}
+
+func NewExecutionRepository() *ExecutionRepository {
+	return &ExecutionRepository{}
+}
+
+// InitExecutionTables creates the executions table and adds the
+// execution_id foreign key column to tasks, mirroring the
+// create-if-missing style InitMccTables uses for its own tables.
+func InitExecutionTables() error {
+	executionsTable := `
+	CREATE TABLE IF NOT EXISTS executions (
+		id VARCHAR(255) PRIMARY KEY,
+		agent_id VARCHAR(255) NOT NULL,
+		trigger VARCHAR(50) NOT NULL,
+		status VARCHAR(50) NOT NULL DEFAULT 'running',
+		total INT NOT NULL DEFAULT 0,
+		succeeded INT NOT NULL DEFAULT 0,
+		failed INT NOT NULL DEFAULT 0,
+		in_progress INT NOT NULL DEFAULT 0,
+		stopped INT NOT NULL DEFAULT 0,
+		started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		ended_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+	if _, err := database.DB.Exec(executionsTable); err != nil {
+		return err
+	}
+
+	addColumn := `ALTER TABLE tasks ADD COLUMN IF NOT EXISTS execution_id VARCHAR(255);`
+	if _, err := database.DB.Exec(addColumn); err != nil {
+		return err
+	}
+
+	// Postgres has no "ADD CONSTRAINT IF NOT EXISTS", so add the FK and
+	// tolerate it already existing from a prior boot.
+	addConstraint := `ALTER TABLE tasks ADD CONSTRAINT fk_tasks_execution FOREIGN KEY (execution_id) REFERENCES executions(id);`
+	if _, err := database.DB.Exec(addConstraint); err != nil && !strings.Contains(err.Error(), "already exists") {
+		return err
+	}
+
+	return nil
+}
+
+// Create starts a new execution for agentID, recording how many child
+// tasks it expects to own.
+func (r *ExecutionRepository) Create(agentID string, trigger ExecutionTrigger, total int) (*Execution, error) {
+	id := uuid.New().String()
+
+	query := `
+		INSERT INTO executions (id, agent_id, trigger, status, total, in_progress)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id, agent_id, trigger, status, total, succeeded, failed, in_progress, stopped, started_at, ended_at, created_at
+	`
+
+	execution := &Execution{}
+	var endedAt sql.NullTime
+
+	err := database.DB.QueryRow(query, id, agentID, trigger, ExecutionStatusRunning, total).Scan(
+		&execution.ID, &execution.AgentID, &execution.Trigger, &execution.Status,
+		&execution.Total, &execution.Succeeded, &execution.Failed, &execution.InProgress, &execution.Stopped,
+		&execution.StartedAt, &endedAt, &execution.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		execution.EndedAt = &endedAt.Time
+	}
+
+	return execution, nil
+}
+
+func (r *ExecutionRepository) FindByID(id string) (*Execution, error) {
+	query := `
+		SELECT id, agent_id, trigger, status, total, succeeded, failed, in_progress, stopped, started_at, ended_at, created_at
+		FROM executions WHERE id = $1
+	`
+
+	execution := &Execution{}
+	var endedAt sql.NullTime
+
+	err := database.DB.QueryRow(query, id).Scan(
+		&execution.ID, &execution.AgentID, &execution.Trigger, &execution.Status,
+		&execution.Total, &execution.Succeeded, &execution.Failed, &execution.InProgress, &execution.Stopped,
+		&execution.StartedAt, &endedAt, &execution.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		execution.EndedAt = &endedAt.Time
+	}
+
+	return execution, nil
+}
+
+func (r *ExecutionRepository) FindAll(limit, offset int) ([]Execution, int, error) {
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*)::int FROM executions`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, agent_id, trigger, status, total, succeeded, failed, in_progress, stopped, started_at, ended_at, created_at
+		FROM executions ORDER BY created_at DESC LIMIT $1 OFFSET $2
+	`
+
+	rows, err := database.DB.Query(query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	executions := []Execution{}
+	for rows.Next() {
+		execution := Execution{}
+		var endedAt sql.NullTime
+
+		if err := rows.Scan(
+			&execution.ID, &execution.AgentID, &execution.Trigger, &execution.Status,
+			&execution.Total, &execution.Succeeded, &execution.Failed, &execution.InProgress, &execution.Stopped,
+			&execution.StartedAt, &endedAt, &execution.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		if endedAt.Valid {
+			execution.EndedAt = &endedAt.Time
+		}
+
+		executions = append(executions, execution)
+	}
+
+	return executions, total, rows.Err()
+}
+
+// RecalculateCounters recomputes an execution's total/succeeded/failed/
+// in_progress/stopped counters from its child tasks' current statuses in
+// a single aggregation query, marking the execution completed once no
+// task is left in_progress. Called whenever a child task's status
+// changes, so counters never drift from the tasks table.
+func (r *ExecutionRepository) RecalculateCounters(executionID string) (*Execution, error) {
+	query := `
+		UPDATE executions e
+		SET total = sub.total,
+			succeeded = sub.succeeded,
+			failed = sub.failed,
+			in_progress = sub.in_progress,
+			stopped = sub.stopped,
+			status = CASE
+				WHEN e.status = 'stopped' THEN 'stopped'
+				WHEN sub.in_progress = 0 AND sub.total > 0 THEN 'completed'
+				ELSE 'running'
+			END,
+			ended_at = CASE
+				WHEN e.ended_at IS NOT NULL THEN e.ended_at
+				WHEN sub.in_progress = 0 AND sub.total > 0 THEN NOW()
+				ELSE NULL
+			END
+		FROM (
+			SELECT
+				COUNT(*)::int AS total,
+				COUNT(*) FILTER (WHERE status = 'completed')::int AS succeeded,
+				COUNT(*) FILTER (WHERE status = 'failed')::int AS failed,
+				COUNT(*) FILTER (WHERE status IN ('pending', 'processing'))::int AS in_progress,
+				COUNT(*) FILTER (WHERE status = 'stopped')::int AS stopped
+			FROM tasks
+			WHERE execution_id = $1
+		) sub
+		WHERE e.id = $1
+		RETURNING e.id, e.agent_id, e.trigger, e.status, e.total, e.succeeded, e.failed, e.in_progress, e.stopped, e.started_at, e.ended_at, e.created_at
+	`
+
+	execution := &Execution{}
+	var endedAt sql.NullTime
+
+	err := database.DB.QueryRow(query, executionID).Scan(
+		&execution.ID, &execution.AgentID, &execution.Trigger, &execution.Status,
+		&execution.Total, &execution.Succeeded, &execution.Failed, &execution.InProgress, &execution.Stopped,
+		&execution.StartedAt, &endedAt, &execution.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		execution.EndedAt = &endedAt.Time
+	}
+
+	return execution, nil
+}
+
+// BackfillFromOrphanTasks creates a single-task Execution for every task
+// that predates the Execution model (execution_id IS NULL), so historical
+// tasks show up alongside the rest in the executions endpoints. Returns
+// the number of executions created.
+func (r *ExecutionRepository) BackfillFromOrphanTasks() (int, error) {
+	rows, err := database.DB.Query(`SELECT id, agent_id FROM tasks WHERE execution_id IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+
+	type orphanTask struct {
+		id, agentID string
+	}
+	var orphans []orphanTask
+	for rows.Next() {
+		var o orphanTask
+		if err := rows.Scan(&o.id, &o.agentID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orphans = append(orphans, o)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	created := 0
+	for _, o := range orphans {
+		execution, err := r.Create(o.agentID, ExecutionTriggerManual, 1)
+		if err != nil {
+			return created, err
+		}
+
+		if _, err := database.DB.Exec(`UPDATE tasks SET execution_id = $1 WHERE id = $2`, execution.ID, o.id); err != nil {
+			return created, err
+		}
+
+		if _, err := r.RecalculateCounters(execution.ID); err != nil {
+			return created, err
+		}
+
+		created++
+	}
+
+	return created, nil
+}
+
+// Stop marks a running execution as stopped. Callers are responsible for
+// also stopping the execution's child tasks (see
+// TaskRepository.StopByExecutionID).
+func (r *ExecutionRepository) Stop(id string) (*Execution, error) {
+	query := `
+		UPDATE executions
+		SET status = $1, ended_at = NOW()
+		WHERE id = $2 AND status = $3
+		RETURNING id, agent_id, trigger, status, total, succeeded, failed, in_progress, stopped, started_at, ended_at, created_at
+	`
+
+	execution := &Execution{}
+	var endedAt sql.NullTime
+
+	err := database.DB.QueryRow(query, ExecutionStatusStopped, id, ExecutionStatusRunning).Scan(
+		&execution.ID, &execution.AgentID, &execution.Trigger, &execution.Status,
+		&execution.Total, &execution.Succeeded, &execution.Failed, &execution.InProgress, &execution.Stopped,
+		&execution.StartedAt, &endedAt, &execution.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if endedAt.Valid {
+		execution.EndedAt = &endedAt.Time
+	}
+
+	return execution, nil
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"database/sql"
-	"log"
 	"strings"
 	"time"
 
@@ -41,8 +40,6 @@ func NewMccRepository() *MccRepository {
 
 // InitMccTables creates the necessary tables if they don't exist
 func InitMccTables() error {
-	log.Println("🛠️ Initializing MCC tables...")
-
 	// 1. MCC Codes Master Table
 	// Using TEXT[] for networks if using Postgres. For generic SQL, might need normalization or JSON string.
 	// Assuming Postgres based on "TEXT[]" request.
@@ -153,6 +150,83 @@ func (r *MccRepository) GetByCode(code string) (*Mcc, error) {
 	return &m, nil
 }
 
+// topAuditMccCodes bounds how many distinct MCC codes AuditSummary ranks in
+// its top-N bucket.
+const topAuditMccCodes = 5
+
+// MccCodeCount is a single entry in AuditSummary's top-N MCC code ranking.
+type MccCodeCount struct {
+	Mcc   string `json:"mcc"`
+	Count int    `json:"count"`
+}
+
+// MccAuditSummary aggregates mcc_audit_logs since a cutoff time: decision
+// counts by source, the most frequently chosen MCC codes, and per-user
+// override counts.
+type MccAuditSummary struct {
+	SourceCounts    map[string]int `json:"source_counts"`
+	TopMccCodes     []MccCodeCount `json:"top_mcc_codes"`
+	OverridesByUser map[string]int `json:"overrides_by_user"`
+}
+
+// AuditSummary aggregates mcc_audit_logs entries recorded since the given
+// cutoff. Implemented as a single query with a bucket discriminator column
+// so the three groupings (source, top MCC codes, per-user overrides) are
+// computed in one round trip rather than reduced in memory.
+func (r *MccRepository) AuditSummary(since time.Time) (*MccAuditSummary, error) {
+	query := `
+		WITH logs AS (
+			SELECT * FROM mcc_audit_logs WHERE timestamp >= $1
+		),
+		by_source AS (
+			SELECT source AS key, COUNT(*)::int AS cnt FROM logs GROUP BY source
+		),
+		by_mcc AS (
+			SELECT mcc AS key, COUNT(*)::int AS cnt FROM logs GROUP BY mcc ORDER BY cnt DESC LIMIT $2
+		),
+		by_user_override AS (
+			SELECT selected_by AS key, COUNT(*)::int AS cnt FROM logs WHERE source = 'override' GROUP BY selected_by
+		)
+		SELECT 'source' AS bucket, key, cnt FROM by_source
+		UNION ALL
+		SELECT 'mcc' AS bucket, key, cnt FROM by_mcc
+		UNION ALL
+		SELECT 'user_override' AS bucket, key, cnt FROM by_user_override
+		ORDER BY bucket, cnt DESC
+	`
+
+	rows, err := database.DB.Query(query, since, topAuditMccCodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &MccAuditSummary{
+		SourceCounts:    map[string]int{},
+		TopMccCodes:     []MccCodeCount{},
+		OverridesByUser: map[string]int{},
+	}
+
+	for rows.Next() {
+		var bucket, key string
+		var count int
+		if err := rows.Scan(&bucket, &key, &count); err != nil {
+			return nil, err
+		}
+
+		switch bucket {
+		case "source":
+			summary.SourceCounts[key] = count
+		case "mcc":
+			summary.TopMccCodes = append(summary.TopMccCodes, MccCodeCount{Mcc: key, Count: count})
+		case "user_override":
+			summary.OverridesByUser[key] = count
+		}
+	}
+
+	return summary, rows.Err()
+}
+
 // CreateAuditLog inserts a new audit log
 func (r *MccRepository) CreateAuditLog(logEntry MccAuditLog) error {
 	query := `
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"go-backend/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledJob is a persisted cron trigger: run the given agent/action on
+// a schedule, fanning out Count tasks under one execution each time it
+// fires.
+type ScheduledJob struct {
+	ID        string          `json:"id"`
+	AgentType string          `json:"agent_type"`
+	Action    string          `json:"action"`
+	Input     json.RawMessage `json:"input"`
+	CronExpr  string          `json:"cron_expr"`
+	Count     int             `json:"count"`
+	Enabled   bool            `json:"enabled"`
+	LastRunAt sql.NullTime    `json:"-"`
+}
+
+// MarshalJSON renders LastRunAt as an RFC3339 string (or null), since
+// sql.NullTime doesn't serialize usefully on its own.
+func (j ScheduledJob) MarshalJSON() ([]byte, error) {
+	type alias ScheduledJob
+	var lastRun *time.Time
+	if j.LastRunAt.Valid {
+		lastRun = &j.LastRunAt.Time
+	}
+	return json.Marshal(struct {
+		alias
+		LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	}{alias: alias(j), LastRunAt: lastRun})
+}
+
+type ScheduledJobRepository struct{}
+
+func NewScheduledJobRepository() *ScheduledJobRepository {
+	return &ScheduledJobRepository{}
+}
+
+// InitScheduledJobTables creates the scheduled_jobs table if it doesn't
+// exist yet, following the same create-if-missing convention as the
+// other Init*Tables functions.
+func InitScheduledJobTables() error {
+	table := `
+	CREATE TABLE IF NOT EXISTS scheduled_jobs (
+		id VARCHAR(255) PRIMARY KEY,
+		agent_type VARCHAR(255) NOT NULL,
+		action VARCHAR(255) NOT NULL,
+		input JSONB NOT NULL DEFAULT '{}'::jsonb,
+		cron_expr VARCHAR(100) NOT NULL,
+		count INT NOT NULL DEFAULT 1,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		last_run_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+	_, err := database.DB.Exec(table)
+	return err
+}
+
+func (r *ScheduledJobRepository) Create(agentType, action string, input map[string]any, cronExpr string, count int) (*ScheduledJob, error) {
+	id := uuid.New().String()
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO scheduled_jobs (id, agent_type, action, input, cron_expr, count, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE)
+		RETURNING id, agent_type, action, input, cron_expr, count, enabled, last_run_at
+	`
+
+	job := &ScheduledJob{}
+	err = database.DB.QueryRow(query, id, agentType, action, inputJSON, cronExpr, count).Scan(
+		&job.ID, &job.AgentType, &job.Action, &job.Input, &job.CronExpr, &job.Count, &job.Enabled, &job.LastRunAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// FindAllEnabled returns every enabled scheduled job, for the scheduler's
+// once-a-minute tick to match against the current time.
+func (r *ScheduledJobRepository) FindAllEnabled() ([]ScheduledJob, error) {
+	query := `
+		SELECT id, agent_type, action, input, cron_expr, count, enabled, last_run_at
+		FROM scheduled_jobs WHERE enabled = TRUE
+	`
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []ScheduledJob{}
+	for rows.Next() {
+		job := ScheduledJob{}
+		if err := rows.Scan(
+			&job.ID, &job.AgentType, &job.Action, &job.Input, &job.CronExpr, &job.Count, &job.Enabled, &job.LastRunAt,
+		); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// MarkRun records that a scheduled job fired, so a retried tick within
+// the same minute (e.g. after a restart) doesn't double-dispatch it.
+func (r *ScheduledJobRepository) MarkRun(id string) error {
+	_, err := database.DB.Exec(`UPDATE scheduled_jobs SET last_run_at = NOW() WHERE id = $1`, id)
+	return err
+}
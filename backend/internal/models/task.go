@@ -3,9 +3,11 @@ package models
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"go-backend/internal/database"
+	"go-backend/internal/sqlbuilder"
 
 	"github.com/google/uuid"
 )
@@ -17,6 +19,7 @@ const (
 	TaskStatusProcessing TaskStatus = "processing"
 	TaskStatusCompleted  TaskStatus = "completed"
 	TaskStatusFailed     TaskStatus = "failed"
+	TaskStatusStopped    TaskStatus = "stopped"
 )
 
 type TaskPriority string
@@ -28,18 +31,24 @@ const (
 )
 
 type Task struct {
-	ID          string          `json:"id"`
-	AgentID     string          `json:"agent_id"`
-	UserID      *string         `json:"user_id,omitempty"`
-	Action      string          `json:"action"`
-	Input       json.RawMessage `json:"input"`
-	Output      json.RawMessage `json:"output,omitempty"`
-	Status      TaskStatus      `json:"status"`
-	Priority    TaskPriority    `json:"priority"`
-	Error       *string         `json:"error,omitempty"`
-	StartedAt   *time.Time      `json:"started_at,omitempty"`
-	CompletedAt *time.Time      `json:"completed_at,omitempty"`
-	CreatedAt   time.Time       `json:"created_at"`
+	ID             string          `json:"id"`
+	AgentID        string          `json:"agent_id"`
+	UserID         *string         `json:"user_id,omitempty"`
+	ExecutionID    *string         `json:"execution_id,omitempty"`
+	JobID          string          `json:"job_id"`
+	Action         string          `json:"action"`
+	Input          json.RawMessage `json:"input"`
+	Output         json.RawMessage `json:"output,omitempty"`
+	Status         TaskStatus      `json:"status"`
+	Priority       TaskPriority    `json:"priority"`
+	Error          *string         `json:"error,omitempty"`
+	CallbackURL    *string         `json:"callback_url,omitempty"`
+	CallbackSecret *string         `json:"-"`
+	SignalCallback bool            `json:"signal_callback"`
+	StartedAt      *time.Time      `json:"started_at,omitempty"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+	LastHeartbeat  *time.Time      `json:"last_heartbeat,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
 }
 
 type TaskRepository struct{}
@@ -48,8 +57,18 @@ func NewTaskRepository() *TaskRepository {
 	return &TaskRepository{}
 }
 
+// Create inserts a standalone task with no owning execution, preserving
+// the original single-task call path used outside the scheduler.
 func (r *TaskRepository) Create(agentID, action string, input map[string]any, priority, userID string) (*Task, error) {
+	return r.CreateWithExecution(agentID, action, input, priority, userID, "")
+}
+
+// CreateWithExecution inserts a task belonging to an execution, so the
+// scheduler can fan an execution out into N child tasks. executionID may
+// be empty, in which case the task has no owning execution.
+func (r *TaskRepository) CreateWithExecution(agentID, action string, input map[string]any, priority, userID, executionID string) (*Task, error) {
 	id := uuid.New().String()
+	jobID := uuid.New().String()
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
 		return nil, err
@@ -60,15 +79,20 @@ func (r *TaskRepository) Create(agentID, action string, input map[string]any, pr
 		userIDPtr = &userID
 	}
 
+	var executionIDPtr *string
+	if executionID != "" {
+		executionIDPtr = &executionID
+	}
+
 	query := `
-		INSERT INTO tasks (id, agent_id, user_id, action, input, status, priority)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, agent_id, user_id, action, input, status, priority, created_at
+		INSERT INTO tasks (id, agent_id, user_id, execution_id, job_id, action, input, status, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, agent_id, user_id, execution_id, job_id, action, input, status, priority, created_at
 	`
 
 	task := &Task{}
-	err = database.DB.QueryRow(query, id, agentID, userIDPtr, action, inputJSON, TaskStatusPending, priority).Scan(
-		&task.ID, &task.AgentID, &task.UserID, &task.Action, &task.Input, &task.Status, &task.Priority, &task.CreatedAt,
+	err = database.DB.QueryRow(query, id, agentID, userIDPtr, executionIDPtr, jobID, action, inputJSON, TaskStatusPending, priority).Scan(
+		&task.ID, &task.AgentID, &task.UserID, &task.ExecutionID, &task.JobID, &task.Action, &task.Input, &task.Status, &task.Priority, &task.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -79,17 +103,19 @@ func (r *TaskRepository) Create(agentID, action string, input map[string]any, pr
 
 func (r *TaskRepository) FindByID(id string) (*Task, error) {
 	query := `
-		SELECT id, agent_id, user_id, action, input, output, status, priority, error, started_at, completed_at, created_at
+		SELECT id, agent_id, user_id, execution_id, job_id, action, input, output, status, priority, error,
+			callback_url, callback_secret, signal_callback, started_at, completed_at, last_heartbeat, created_at
 		FROM tasks WHERE id = $1
 	`
 
 	task := &Task{}
-	var output, errorMsg sql.NullString
-	var startedAt, completedAt sql.NullTime
+	var jobID, output, errorMsg, callbackURL, callbackSecret sql.NullString
+	var startedAt, completedAt, lastHeartbeat sql.NullTime
 
 	err := database.DB.QueryRow(query, id).Scan(
-		&task.ID, &task.AgentID, &task.UserID, &task.Action, &task.Input,
-		&output, &task.Status, &task.Priority, &errorMsg, &startedAt, &completedAt, &task.CreatedAt,
+		&task.ID, &task.AgentID, &task.UserID, &task.ExecutionID, &jobID, &task.Action, &task.Input,
+		&output, &task.Status, &task.Priority, &errorMsg,
+		&callbackURL, &callbackSecret, &task.SignalCallback, &startedAt, &completedAt, &lastHeartbeat, &task.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -98,15 +124,27 @@ func (r *TaskRepository) FindByID(id string) (*Task, error) {
 		return nil, err
 	}
 
+	if jobID.Valid {
+		task.JobID = jobID.String
+	}
 	if output.Valid {
 		task.Output = json.RawMessage(output.String)
 	}
 	if errorMsg.Valid {
 		task.Error = &errorMsg.String
 	}
+	if callbackURL.Valid {
+		task.CallbackURL = &callbackURL.String
+	}
+	if callbackSecret.Valid {
+		task.CallbackSecret = &callbackSecret.String
+	}
 	if startedAt.Valid {
 		task.StartedAt = &startedAt.Time
 	}
+	if lastHeartbeat.Valid {
+		task.LastHeartbeat = &lastHeartbeat.Time
+	}
 	if completedAt.Valid {
 		task.CompletedAt = &completedAt.Time
 	}
@@ -114,71 +152,103 @@ func (r *TaskRepository) FindByID(id string) (*Task, error) {
 	return task, nil
 }
 
-func (r *TaskRepository) FindAll(filters map[string]any) ([]Task, int, error) {
-	query := `SELECT id, agent_id, user_id, action, input, output, status, priority, error, started_at, completed_at, created_at FROM tasks WHERE 1=1`
-	countQuery := `SELECT COUNT(*)::int FROM tasks WHERE 1=1`
-	args := []any{}
-	argCount := 1
-
-	if agentID, ok := filters["agentId"].(string); ok && agentID != "" {
-		query += ` AND agent_id = $` + string(rune('0'+argCount))
-		countQuery += ` AND agent_id = $` + string(rune('0'+argCount))
-		args = append(args, agentID)
-		argCount++
+// FindAll returns tasks matching filters, paginated either by the
+// default limit/offset or, when filters["cursor"] holds a previous
+// page's next_cursor, by keyset on (created_at, id) - the latter stays
+// fast on deep pages since it doesn't make Postgres skip OFFSET rows.
+// filters recognizes "agentId", "executionId", "status", "userId",
+// "priority" (all string equality), "createdAfter"/"createdBefore"
+// (time.Time range on created_at), "cursor" (string), "limit" (int), and
+// "offset" (int, ignored when cursor is set). total is the full match
+// count under limit/offset mode and 0 under cursor mode, where counting
+// every row up front would defeat the point of keyset pagination.
+// nextCursor is non-empty whenever the page was full, so the caller can
+// always follow up with ?cursor= regardless of which mode fetched this
+// page.
+func (r *TaskRepository) FindAll(filters map[string]any) (tasks []Task, total int, nextCursor string, err error) {
+	columns := "id, agent_id, user_id, execution_id, action, input, output, status, priority, error, started_at, completed_at, created_at"
+
+	var eqFilters []sqlbuilder.Filter
+	if v, ok := filters["agentId"].(string); ok {
+		eqFilters = append(eqFilters, sqlbuilder.Filter{Column: "agent_id", Value: v})
+	}
+	if v, ok := filters["executionId"].(string); ok {
+		eqFilters = append(eqFilters, sqlbuilder.Filter{Column: "execution_id", Value: v})
+	}
+	if v, ok := filters["status"].(string); ok {
+		eqFilters = append(eqFilters, sqlbuilder.Filter{Column: "status", Value: v})
+	}
+	if v, ok := filters["userId"].(string); ok {
+		eqFilters = append(eqFilters, sqlbuilder.Filter{Column: "user_id", Value: v})
+	}
+	if v, ok := filters["priority"].(string); ok {
+		eqFilters = append(eqFilters, sqlbuilder.Filter{Column: "priority", Value: v})
 	}
 
-	if status, ok := filters["status"].(string); ok && status != "" {
-		query += ` AND status = $` + string(rune('0'+argCount))
-		countQuery += ` AND status = $` + string(rune('0'+argCount))
-		args = append(args, status)
-		argCount++
+	var ranges []sqlbuilder.RangeFilter
+	createdAfter, _ := filters["createdAfter"].(time.Time)
+	createdBefore, _ := filters["createdBefore"].(time.Time)
+	if !createdAfter.IsZero() || !createdBefore.IsZero() {
+		ranges = append(ranges, sqlbuilder.RangeFilter{Column: "created_at", From: createdAfter, To: createdBefore})
 	}
 
-	query += ` ORDER BY created_at DESC`
+	query, countQuery, args, nextArg := sqlbuilder.BuildSelect("tasks", columns, eqFilters, ranges)
 
 	limit := 50
-	offset := 0
 	if l, ok := filters["limit"].(int); ok && l > 0 {
 		limit = l
 	}
-	if o, ok := filters["offset"].(int); ok && o > 0 {
-		offset = o
+
+	cursor, usingCursor := filters["cursor"].(string)
+	usingCursor = usingCursor && cursor != ""
+
+	if usingCursor {
+		c, decErr := sqlbuilder.DecodeCursor(cursor)
+		if decErr != nil {
+			return nil, 0, "", decErr
+		}
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", nextArg, nextArg+1)
+		args = append(args, c.CreatedAt, c.ID)
+		nextArg += 2
+	} else {
+		if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, "", err
+		}
 	}
 
-	query += ` LIMIT $` + string(rune('0'+argCount))
-	args = append(args, limit)
-	argCount++
+	query += " ORDER BY created_at DESC, id DESC"
 
-	query += ` OFFSET $` + string(rune('0'+argCount))
-	args = append(args, offset)
+	query += fmt.Sprintf(" LIMIT $%d", nextArg)
+	args = append(args, limit)
+	nextArg++
 
-	// Get total count
-	var total int
-	countArgs := args[:len(args)-2] // Exclude limit and offset
-	err := database.DB.QueryRow(countQuery, countArgs...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+	if !usingCursor {
+		offset := 0
+		if o, ok := filters["offset"].(int); ok && o > 0 {
+			offset = o
+		}
+		query += fmt.Sprintf(" OFFSET $%d", nextArg)
+		args = append(args, offset)
+		nextArg++
 	}
 
-	// Get tasks
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, "", err
 	}
 	defer rows.Close()
 
-	tasks := []Task{}
+	tasks = []Task{}
 	for rows.Next() {
 		task := Task{}
 		var output, errorMsg sql.NullString
 		var startedAt, completedAt sql.NullTime
 
-		err := rows.Scan(
-			&task.ID, &task.AgentID, &task.UserID, &task.Action, &task.Input,
+		if err := rows.Scan(
+			&task.ID, &task.AgentID, &task.UserID, &task.ExecutionID, &task.Action, &task.Input,
 			&output, &task.Status, &task.Priority, &errorMsg, &startedAt, &completedAt, &task.CreatedAt,
-		)
-		if err != nil {
-			return nil, 0, err
+		); err != nil {
+			return nil, 0, "", err
 		}
 
 		if output.Valid {
@@ -196,33 +266,190 @@ func (r *TaskRepository) FindAll(filters map[string]any) ([]Task, int, error) {
 
 		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", err
+	}
 
-	return tasks, total, nil
+	if len(tasks) == limit {
+		last := tasks[len(tasks)-1]
+		nextCursor = sqlbuilder.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return tasks, total, nextCursor, nil
 }
 
-func (r *TaskRepository) UpdateStatus(id string, status TaskStatus) error {
-	query := `UPDATE tasks SET status = $1, started_at = NOW() WHERE id = $2`
-	_, err := database.DB.Exec(query, status, id)
+// update runs a partial update against the tasks table through the shared
+// sqlbuilder, so placeholder numbering and jsonb coercion for "output" go
+// through the same tested path as AgentRepository.Update.
+func (r *TaskRepository) update(id string, updates map[string]any) error {
+	query, args, err := sqlbuilder.BuildUpdate("tasks", updates, "id", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(query, args...)
 	return err
 }
 
-func (r *TaskRepository) UpdateCompleted(id string, output map[string]any) error {
-	outputJSON, err := json.Marshal(output)
+func (r *TaskRepository) UpdateStatus(id string, status TaskStatus) error {
+	return r.update(id, map[string]any{
+		"status":     status,
+		"started_at": time.Now(),
+	})
+}
+
+// UpdateCompleted marks a task completed, but only if it's still pending
+// or processing - a task a concurrent Stop already moved to "stopped"
+// stays stopped even if the goroutine that was running it (unaware of
+// the stop) finishes afterward. ok reports whether the task was active
+// and therefore updated; callers that care only about errors can ignore it.
+func (r *TaskRepository) UpdateCompleted(id string, output map[string]any) (ok bool, err error) {
+	ok, err = r.updateIfActive(id, map[string]any{
+		"status":       TaskStatusCompleted,
+		"output":       output,
+		"completed_at": time.Now(),
+	})
+	if err != nil || !ok {
+		return ok, err
+	}
+	return ok, r.maybeEnqueueCallback(id)
+}
+
+// UpdateFailed marks a task failed, subject to the same active-only guard
+// as UpdateCompleted.
+func (r *TaskRepository) UpdateFailed(id string, errorMsg string) (ok bool, err error) {
+	ok, err = r.updateIfActive(id, map[string]any{
+		"status":       TaskStatusFailed,
+		"error":        errorMsg,
+		"completed_at": time.Now(),
+	})
+	if err != nil || !ok {
+		return ok, err
+	}
+	return ok, r.maybeEnqueueCallback(id)
+}
+
+// updateIfActive runs update, additionally requiring the row to still be
+// pending or processing, so a finish arriving after the task was already
+// stopped (or otherwise finished) can't clobber it. ok reports whether the
+// row matched and was updated.
+func (r *TaskRepository) updateIfActive(id string, updates map[string]any) (ok bool, err error) {
+	query, args, err := sqlbuilder.BuildUpdate("tasks", updates, "id", id)
+	if err != nil {
+		return false, err
+	}
+
+	query += fmt.Sprintf(" AND status IN ($%d, $%d)", len(args)+1, len(args)+2)
+	args = append(args, TaskStatusPending, TaskStatusProcessing)
+
+	res, err := database.DB.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// SetCallback records a webhook to notify once the task finishes.
+// signalCallback lets a caller register the URL/secret ahead of time
+// without yet opting into delivery (e.g. while still assembling the
+// payload), since UpdateCompleted/UpdateFailed only enqueue a callback
+// when it's true.
+func (r *TaskRepository) SetCallback(id, callbackURL, callbackSecret string, signalCallback bool) error {
+	return r.update(id, map[string]any{
+		"callback_url":    callbackURL,
+		"callback_secret": callbackSecret,
+		"signal_callback": signalCallback,
+	})
+}
+
+// maybeEnqueueCallback schedules a webhook delivery attempt if the task
+// opted into one, leaving the actual HTTP call to the callbacks package's
+// background dispatcher rather than blocking the caller (UpdateCompleted/
+// UpdateFailed run inside the executor's goroutine).
+func (r *TaskRepository) maybeEnqueueCallback(id string) error {
+	var signalCallback bool
+	var callbackURL sql.NullString
+	err := database.DB.QueryRow(`SELECT signal_callback, callback_url FROM tasks WHERE id = $1`, id).Scan(&signalCallback, &callbackURL)
 	if err != nil {
 		return err
 	}
+	if !signalCallback || !callbackURL.Valid || callbackURL.String == "" {
+		return nil
+	}
 
-	query := `UPDATE tasks SET status = $1, output = $2, completed_at = NOW() WHERE id = $3`
-	_, err = database.DB.Exec(query, TaskStatusCompleted, outputJSON, id)
-	return err
+	return NewTaskCallbackRepository().Enqueue(id)
 }
 
-func (r *TaskRepository) UpdateFailed(id string, errorMsg string) error {
-	query := `UPDATE tasks SET status = $1, error = $2, completed_at = NOW() WHERE id = $3`
-	_, err := database.DB.Exec(query, TaskStatusFailed, errorMsg, id)
+// StopByExecutionID marks every not-yet-finished task under an execution
+// as stopped, so that stopping an execution (e.g. via POST
+// /api/executions/:id/stop) stops its still-running child tasks too.
+func (r *TaskRepository) StopByExecutionID(executionID string) error {
+	query := `
+		UPDATE tasks
+		SET status = $1, completed_at = NOW()
+		WHERE execution_id = $2 AND status IN ($3, $4)
+	`
+	_, err := database.DB.Exec(query, TaskStatusStopped, executionID, TaskStatusPending, TaskStatusProcessing)
 	return err
 }
 
+// MergeOutput shallow-merges patch into the task's output JSON with a
+// single `COALESCE(output, '{}'::jsonb) || patch` statement, so the merge
+// happens atomically server-side instead of a Go-side read-modify-write
+// that could lose a concurrent writer's keys. The guarantee comes from
+// Postgres applying the whole UPDATE as one statement: two callers
+// merging different keys at the same time each see the other's key in
+// the final row, regardless of which commits first.
+// TestMergeOutputConcurrentKeys checks this against a real Postgres
+// instance when TEST_DATABASE_URL is set.
+func (r *TaskRepository) MergeOutput(taskID string, patch map[string]any) (*Task, error) {
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE tasks
+		SET output = COALESCE(output, '{}'::jsonb) || $1::jsonb
+		WHERE id = $2
+		RETURNING id, agent_id, user_id, action, input, output, status, priority, error, started_at, completed_at, created_at
+	`
+
+	task := &Task{}
+	var output, errorMsg sql.NullString
+	var startedAt, completedAt sql.NullTime
+
+	err = database.DB.QueryRow(query, patchJSON, taskID).Scan(
+		&task.ID, &task.AgentID, &task.UserID, &task.Action, &task.Input,
+		&output, &task.Status, &task.Priority, &errorMsg, &startedAt, &completedAt, &task.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Valid {
+		task.Output = json.RawMessage(output.String)
+	}
+	if errorMsg.Valid {
+		task.Error = &errorMsg.String
+	}
+	if startedAt.Valid {
+		task.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+
+	return task, nil
+}
+
 func (r *TaskRepository) GetStatusCounts(agentID string) (map[string]int, error) {
 	query := `SELECT status, COUNT(*)::int FROM tasks`
 	args := []any{}
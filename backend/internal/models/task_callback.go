@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"go-backend/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// TaskCallback is one queued webhook delivery attempt for a task that
+// opted into callbacks. A background dispatcher (internal/callbacks)
+// drains due entries and retries with exponential backoff on failure.
+type TaskCallback struct {
+	ID            string    `json:"id"`
+	TaskID        string    `json:"task_id"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     *string   `json:"last_error,omitempty"`
+	Delivered     bool      `json:"delivered"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type TaskCallbackRepository struct{}
+
+func NewTaskCallbackRepository() *TaskCallbackRepository {
+	return &TaskCallbackRepository{}
+}
+
+// InitTaskCallbackTables adds the callback columns to tasks and creates
+// the task_callbacks (delivery queue) and task_resume_keys (idempotency
+// ledger for POST /api/tasks/:id/resume) tables, mirroring the
+// create-if-missing style InitExecutionTables uses to extend tasks.
+func InitTaskCallbackTables() error {
+	addColumns := `
+		ALTER TABLE tasks ADD COLUMN IF NOT EXISTS callback_url VARCHAR(2048);
+		ALTER TABLE tasks ADD COLUMN IF NOT EXISTS callback_secret VARCHAR(255);
+		ALTER TABLE tasks ADD COLUMN IF NOT EXISTS signal_callback BOOLEAN NOT NULL DEFAULT FALSE;
+	`
+	if _, err := database.DB.Exec(addColumns); err != nil {
+		return err
+	}
+
+	callbacksTable := `
+	CREATE TABLE IF NOT EXISTS task_callbacks (
+		id VARCHAR(255) PRIMARY KEY,
+		task_id VARCHAR(255) NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_error TEXT,
+		delivered BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+	if _, err := database.DB.Exec(callbacksTable); err != nil {
+		return err
+	}
+
+	resumeKeysTable := `
+	CREATE TABLE IF NOT EXISTS task_resume_keys (
+		idempotency_key VARCHAR(255) PRIMARY KEY,
+		task_id VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+	_, err := database.DB.Exec(resumeKeysTable)
+	return err
+}
+
+// Enqueue schedules an immediate first delivery attempt for taskID.
+func (r *TaskCallbackRepository) Enqueue(taskID string) error {
+	_, err := database.DB.Exec(
+		`INSERT INTO task_callbacks (id, task_id, next_attempt_at) VALUES ($1, $2, NOW())`,
+		uuid.New().String(), taskID,
+	)
+	return err
+}
+
+// FindDue returns up to limit undelivered callbacks whose next attempt is
+// due, for the background dispatcher's poll loop.
+func (r *TaskCallbackRepository) FindDue(limit int) ([]TaskCallback, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, task_id, attempts, next_attempt_at, last_error, delivered, created_at
+		FROM task_callbacks
+		WHERE delivered = FALSE AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	callbacks := []TaskCallback{}
+	for rows.Next() {
+		cb := TaskCallback{}
+		var lastError sql.NullString
+		if err := rows.Scan(&cb.ID, &cb.TaskID, &cb.Attempts, &cb.NextAttemptAt, &lastError, &cb.Delivered, &cb.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lastError.Valid {
+			cb.LastError = &lastError.String
+		}
+		callbacks = append(callbacks, cb)
+	}
+
+	return callbacks, rows.Err()
+}
+
+// MarkDelivered records a successful delivery.
+func (r *TaskCallbackRepository) MarkDelivered(id string) error {
+	_, err := database.DB.Exec(`UPDATE task_callbacks SET delivered = TRUE, last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+// MarkFailedAttempt records a failed delivery attempt and schedules the
+// next one at nextAttemptAt (the caller computes the exponential
+// backoff).
+func (r *TaskCallbackRepository) MarkFailedAttempt(id, errMsg string, nextAttemptAt time.Time) error {
+	_, err := database.DB.Exec(
+		`UPDATE task_callbacks SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2 WHERE id = $3`,
+		errMsg, nextAttemptAt, id,
+	)
+	return err
+}
+
+// ResumeKeyTaskID looks up the task an idempotency key was already
+// claimed against, without claiming it. Resume uses this to tell a
+// genuine conflict (key never claimed, so there's nothing to replay)
+// apart from a replay of an already-applied result (key claimed, task
+// has since moved past pending) when the task isn't pending anymore.
+func (r *TaskCallbackRepository) ResumeKeyTaskID(idempotencyKey string) (taskID string, found bool, err error) {
+	err = database.DB.QueryRow(
+		`SELECT task_id FROM task_resume_keys WHERE idempotency_key = $1`,
+		idempotencyKey,
+	).Scan(&taskID)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return taskID, true, nil
+}
+
+// ClaimResume atomically registers an idempotency key against taskID,
+// returning claimed=false if the key has already been used - the caller
+// should then treat the request as a no-op replay rather than applying
+// the result a second time.
+func (r *TaskCallbackRepository) ClaimResume(idempotencyKey, taskID string) (claimed bool, err error) {
+	res, err := database.DB.Exec(
+		`INSERT INTO task_resume_keys (idempotency_key, task_id) VALUES ($1, $2) ON CONFLICT (idempotency_key) DO NOTHING`,
+		idempotencyKey, taskID,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
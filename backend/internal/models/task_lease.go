@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go-backend/internal/database"
+)
+
+// InitTaskLeaseTables adds the columns tools.Executor's lease supervisor
+// needs: job_id identifies a task's own run of the executor, and
+// last_heartbeat is bumped every 60s while that run is in flight so a
+// stale value reveals a task whose owning backend instance crashed
+// mid-run.
+func InitTaskLeaseTables() error {
+	alter := `
+		ALTER TABLE tasks ADD COLUMN IF NOT EXISTS job_id VARCHAR(255);
+		ALTER TABLE tasks ADD COLUMN IF NOT EXISTS last_heartbeat TIMESTAMP;
+	`
+	_, err := database.DB.Exec(alter)
+	return err
+}
+
+// Heartbeat bumps id's last_heartbeat to now, renewing its lease.
+func (r *TaskRepository) Heartbeat(id string) error {
+	_, err := database.DB.Exec(`UPDATE tasks SET last_heartbeat = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// FindStaleProcessing returns the IDs of tasks still marked processing
+// whose lease has gone quiet - no heartbeat, or if one was never
+// recorded, no started_at - more recent than olderThan. Used by the
+// lease supervisor to find tasks left behind by a crashed backend
+// instance so they can be marked failed instead of hanging forever.
+func (r *TaskRepository) FindStaleProcessing(olderThan time.Time) ([]string, error) {
+	query := `
+		SELECT id FROM tasks
+		WHERE status = $1
+		AND COALESCE(last_heartbeat, started_at, created_at) < $2
+	`
+	rows, err := database.DB.Query(query, TaskStatusProcessing, olderThan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
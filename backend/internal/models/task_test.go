@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+
+	"go-backend/internal/database"
+)
+
+// TestMergeOutputConcurrentKeys is the integration test MergeOutput's doc
+// comment promises: two callers merging different keys at the same time
+// must both survive, which is the whole reason MergeOutput does the
+// merge as a single `COALESCE(output, '{}'::jsonb) || patch` statement
+// instead of a Go-side read-modify-write. Requires a real Postgres
+// instance (this repo has no test double for database.DB) reachable at
+// TEST_DATABASE_URL; skipped otherwise.
+func TestMergeOutputConcurrentKeys(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test against a real Postgres instance")
+	}
+	if err := database.Connect(dbURL); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer database.Close()
+
+	repo := NewTaskRepository()
+	task, err := repo.Create("agent-merge-output-test", "test", map[string]any{}, "medium", "")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer database.DB.Exec(`DELETE FROM tasks WHERE id = $1`, task.ID)
+
+	var wg sync.WaitGroup
+	var errA, errB error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, errA = repo.MergeOutput(task.ID, map[string]any{"a": 1})
+	}()
+	go func() {
+		defer wg.Done()
+		_, errB = repo.MergeOutput(task.ID, map[string]any{"b": 2})
+	}()
+	wg.Wait()
+
+	if errA != nil {
+		t.Fatalf("MergeOutput(a): %v", errA)
+	}
+	if errB != nil {
+		t.Fatalf("MergeOutput(b): %v", errB)
+	}
+
+	final, err := repo.FindByID(task.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal(final.Output, &output); err != nil {
+		t.Fatalf("unmarshal output: %v", err)
+	}
+	if _, ok := output["a"]; !ok {
+		t.Errorf("expected concurrently-written key %q to survive, got output = %v", "a", output)
+	}
+	if _, ok := output["b"]; !ok {
+		t.Errorf("expected concurrently-written key %q to survive, got output = %v", "b", output)
+	}
+}
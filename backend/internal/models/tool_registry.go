@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/tools"
+)
+
+// ToolRegistryRepository persists the contents of tools.Registry so a
+// restart (or a crash before the manifest is re-read) doesn't lose
+// registrations made by editing the manifest and reloading.
+type ToolRegistryRepository struct{}
+
+func NewToolRegistryRepository() *ToolRegistryRepository {
+	return &ToolRegistryRepository{}
+}
+
+// InitToolRegistryTables creates the tools_registry table if it doesn't
+// exist yet, following the same create-if-missing convention as the
+// other Init*Tables functions.
+func InitToolRegistryTables() error {
+	table := `
+	CREATE TABLE IF NOT EXISTS tools_registry (
+		name VARCHAR(255) PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		command VARCHAR(255) NOT NULL,
+		args JSONB NOT NULL DEFAULT '[]'::jsonb,
+		timeout_seconds INT NOT NULL DEFAULT 0,
+		working_dir VARCHAR(255) NOT NULL DEFAULT '.',
+		concurrency INT NOT NULL DEFAULT 0,
+		agent_type VARCHAR(255) NOT NULL,
+		env JSONB NOT NULL DEFAULT '{}'::jsonb,
+		health_check_cmd TEXT NOT NULL DEFAULT '',
+		backend VARCHAR(50) NOT NULL DEFAULT 'local',
+		image VARCHAR(255) NOT NULL DEFAULT '',
+		worker_selector JSONB NOT NULL DEFAULT '{}'::jsonb,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`
+	_, err := database.DB.Exec(table)
+	return err
+}
+
+// SyncAll replaces the persisted registry with the given tools: every
+// tool is upserted by name, and any row no longer present is deleted, so
+// the table always mirrors the in-memory registry after a load/reload.
+func (r *ToolRegistryRepository) SyncAll(toolList []tools.ToolConfig) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	names := make([]string, 0, len(toolList))
+	for _, tool := range toolList {
+		argsJSON, err := json.Marshal(tool.Args)
+		if err != nil {
+			return err
+		}
+		envJSON, err := json.Marshal(tool.Env)
+		if err != nil {
+			return err
+		}
+		workerSelectorJSON, err := json.Marshal(tool.WorkerSelector)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO tools_registry (name, description, command, args, timeout_seconds, working_dir, concurrency, agent_type, env, health_check_cmd, backend, image, worker_selector, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
+			ON CONFLICT (name) DO UPDATE SET
+				description = EXCLUDED.description,
+				command = EXCLUDED.command,
+				args = EXCLUDED.args,
+				timeout_seconds = EXCLUDED.timeout_seconds,
+				working_dir = EXCLUDED.working_dir,
+				concurrency = EXCLUDED.concurrency,
+				agent_type = EXCLUDED.agent_type,
+				env = EXCLUDED.env,
+				health_check_cmd = EXCLUDED.health_check_cmd,
+				backend = EXCLUDED.backend,
+				image = EXCLUDED.image,
+				worker_selector = EXCLUDED.worker_selector,
+				updated_at = NOW()
+		`, tool.Name, tool.Description, tool.Command, argsJSON, int(tool.Timeout.Seconds()), tool.WorkingDir, tool.ConcurrencyLimit, tool.AgentType, envJSON, tool.HealthCheckCmd, tool.Backend, tool.Image, workerSelectorJSON)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, tool.Name)
+	}
+
+	if len(names) == 0 {
+		if _, err := tx.Exec(`DELETE FROM tools_registry`); err != nil {
+			return err
+		}
+	} else {
+		query := `DELETE FROM tools_registry WHERE name NOT IN (SELECT unnest($1::text[]))`
+		if _, err := tx.Exec(query, toPqTextArray(names)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindAll returns every persisted tool registration, for operators
+// inspecting what's currently registered without going through the
+// manifest file.
+func (r *ToolRegistryRepository) FindAll() ([]tools.ToolConfig, error) {
+	rows, err := database.DB.Query(`
+		SELECT name, description, command, args, timeout_seconds, working_dir, concurrency, agent_type, env, health_check_cmd, backend, image, worker_selector
+		FROM tools_registry ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []tools.ToolConfig{}
+	for rows.Next() {
+		var tool tools.ToolConfig
+		var argsJSON, envJSON, workerSelectorJSON []byte
+		var timeoutSeconds int
+		if err := rows.Scan(&tool.Name, &tool.Description, &tool.Command, &argsJSON, &timeoutSeconds, &tool.WorkingDir, &tool.ConcurrencyLimit, &tool.AgentType, &envJSON, &tool.HealthCheckCmd, &tool.Backend, &tool.Image, &workerSelectorJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(argsJSON, &tool.Args); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(envJSON, &tool.Env); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(workerSelectorJSON, &tool.WorkerSelector); err != nil {
+			return nil, err
+		}
+		tool.Timeout = time.Duration(timeoutSeconds) * time.Second
+		result = append(result, tool)
+	}
+
+	return result, rows.Err()
+}
+
+// toPqTextArray formats a Go string slice as a Postgres array literal,
+// mirroring the manual approach seed.SeedMccCodes uses for TEXT[] columns
+// without pulling in pq.Array.
+func toPqTextArray(values []string) string {
+	literal := "{"
+	for i, v := range values {
+		if i > 0 {
+			literal += ","
+		}
+		literal += `"` + v + `"`
+	}
+	literal += "}"
+	return literal
+}
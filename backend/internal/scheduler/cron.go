@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMatches reports whether now falls on a standard 5-field cron
+// expression (minute hour day-of-month month day-of-week), evaluated to
+// the minute. Each field supports "*", exact numbers, "*/N" steps, and
+// comma-separated lists; it does not support ranges ("1-5") or named
+// months/weekdays, which covers what the scheduled jobs in this system
+// need.
+func cronMatches(expr string, now time.Time) bool {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return false
+	}
+
+	return fieldMatches(fields[0], now.Minute()) &&
+		fieldMatches(fields[1], now.Hour()) &&
+		fieldMatches(fields[2], now.Day()) &&
+		fieldMatches(fields[3], int(now.Month())) &&
+		fieldMatches(fields[4], int(now.Weekday()))
+}
+
+func fieldMatches(field string, value int) bool {
+	for _, part := range strings.Split(field, ",") {
+		if part == "*" {
+			return true
+		}
+		if step, ok := strings.CutPrefix(part, "*/"); ok {
+			if n, err := strconv.Atoi(step); err == nil && n > 0 && value%n == 0 {
+				return true
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(part); err == nil && n == value {
+			return true
+		}
+	}
+	return false
+}
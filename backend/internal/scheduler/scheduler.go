@@ -0,0 +1,193 @@
+// Package scheduler dispatches agent executions from three trigger
+// kinds - manual (a direct API call), scheduled (cron expressions
+// persisted in the scheduled_jobs table, matched by a single ticking
+// goroutine), and event (posted to a webhook endpoint) - all converging
+// on Trigger, which creates one Execution and fans it out into N child
+// tasks run through the existing tools.Executor.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-backend/internal/models"
+	"go-backend/internal/tools"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Scheduler owns the once-a-minute cron tick loop and the shared Trigger
+// path used by all three trigger kinds.
+type Scheduler struct {
+	agentRepo     *models.AgentRepository
+	executionRepo *models.ExecutionRepository
+	taskRepo      *models.TaskRepository
+	jobRepo       *models.ScheduledJobRepository
+	executor      *tools.Executor
+	logger        hclog.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(
+	agentRepo *models.AgentRepository,
+	executionRepo *models.ExecutionRepository,
+	taskRepo *models.TaskRepository,
+	jobRepo *models.ScheduledJobRepository,
+	executor *tools.Executor,
+	logger hclog.Logger,
+) *Scheduler {
+	return &Scheduler{
+		agentRepo:     agentRepo,
+		executionRepo: executionRepo,
+		taskRepo:      taskRepo,
+		jobRepo:       jobRepo,
+		executor:      executor,
+		logger:        logger,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start runs the cron tick loop in the background, ticking once a
+// minute. This process is the sole leader for scheduled dispatch -
+// running more than one instance would double-dispatch scheduled jobs,
+// since there is no distributed lock backing this.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.tick(now)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop signals the tick loop to exit and waits for it to finish.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	jobs, err := s.jobRepo.FindAllEnabled()
+	if err != nil {
+		s.logger.Error("failed to load scheduled jobs", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if !cronMatches(job.CronExpr, now) {
+			continue
+		}
+		if job.LastRunAt.Valid && job.LastRunAt.Time.Truncate(time.Minute).Equal(now.Truncate(time.Minute)) {
+			continue // already dispatched for this minute
+		}
+
+		var input map[string]any
+		if err := json.Unmarshal(job.Input, &input); err != nil {
+			input = map[string]any{}
+		}
+
+		if _, err := s.Trigger(context.Background(), models.ExecutionTriggerScheduled, job.AgentType, job.Action, input, job.Count, ""); err != nil {
+			s.logger.Error("scheduled job dispatch failed", "job_id", job.ID, "error", err)
+			continue
+		}
+		if err := s.jobRepo.MarkRun(job.ID); err != nil {
+			s.logger.Warn("failed to record scheduled job run", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+// Trigger looks up the agent and its tool, creates an execution, and
+// fans it out into count child tasks. Each task runs asynchronously
+// through the executor - the same pattern AgentsHandler.Execute uses for
+// a single task - with the execution's counters recalculated after every
+// child task's status change.
+func (s *Scheduler) Trigger(ctx context.Context, trigger models.ExecutionTrigger, agentType, action string, input map[string]any, count int, userID string) (*models.Execution, error) {
+	agent, err := s.agentRepo.FindByType(agentType)
+	if err != nil {
+		return nil, err
+	}
+	if agent == nil {
+		return nil, fmt.Errorf("agent not found for type: %s", agentType)
+	}
+
+	tool, exists := tools.GetToolByAgentType(agentType)
+	if !exists {
+		return nil, fmt.Errorf("no CLI tool configured for agent type: %s", agentType)
+	}
+
+	if count <= 0 {
+		count = 1
+	}
+
+	execution, err := s.executionRepo.Create(agent.ID, trigger, count)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < count; i++ {
+		task, err := s.taskRepo.CreateWithExecution(agent.ID, action, input, string(models.TaskPriorityMedium), userID, execution.ID)
+		if err != nil {
+			s.logger.Error("failed to create task for execution", "execution_id", execution.ID, "error", err)
+			continue
+		}
+		s.runTask(tool, task, execution.ID)
+	}
+
+	return execution, nil
+}
+
+// runTask executes a single child task and keeps its owning execution's
+// counters in sync before and after the run.
+func (s *Scheduler) runTask(tool tools.ToolConfig, task *models.Task, executionID string) {
+	go func() {
+		s.taskRepo.UpdateStatus(task.ID, models.TaskStatusProcessing)
+		s.recalculateCounters(executionID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), tool.Timeout)
+		defer cancel()
+
+		cliInput := map[string]any{
+			"action":  task.Action,
+			"task_id": task.ID,
+		}
+		var taskInput map[string]any
+		if err := json.Unmarshal(task.Input, &taskInput); err == nil {
+			for k, v := range taskInput {
+				cliInput[k] = v
+			}
+		}
+
+		result, err := s.executor.Execute(ctx, task.ID, tool, cliInput)
+		switch {
+		case err != nil:
+			s.logger.Error("scheduler task execution error", "task_id", task.ID, "error", err)
+			s.taskRepo.UpdateFailed(task.ID, err.Error())
+		case result.Status == "failed":
+			s.taskRepo.UpdateFailed(task.ID, result.Error)
+		default:
+			s.taskRepo.UpdateCompleted(task.ID, result.Output)
+		}
+
+		s.recalculateCounters(executionID)
+	}()
+}
+
+func (s *Scheduler) recalculateCounters(executionID string) {
+	if _, err := s.executionRepo.RecalculateCounters(executionID); err != nil {
+		s.logger.Warn("failed to recalculate execution counters", "execution_id", executionID, "error", err)
+	}
+}
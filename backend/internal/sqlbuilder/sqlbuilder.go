@@ -0,0 +1,208 @@
+// Package sqlbuilder provides small, shared helpers for assembling
+// parameterized SQL statements, so models don't each hand-roll placeholder
+// numbering (a past source of bugs when argument counts passed single
+// digits).
+package sqlbuilder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Condition is an additional "column = value" clause ANDed onto a
+// BuildUpdate WHERE, used for checks beyond the primary row lookup (e.g.
+// optimistic-concurrency's "updated_at = $N").
+type Condition struct {
+	Column string
+	Value  any
+}
+
+// jsonColumns names update columns that hold jsonb data and must be
+// marshaled to JSON explicitly rather than passed through as-is.
+var jsonColumns = map[string]bool{
+	"config": true,
+	"output": true,
+}
+
+// AllowedColumns maps each table to the set of columns BuildUpdate will
+// accept in its updates map. Keys outside this set are rejected rather
+// than interpolated as SQL identifiers, since updates maps in this
+// codebase are often built from client-supplied JSON.
+var AllowedColumns = map[string]map[string]bool{
+	"agents": {
+		"name":        true,
+		"description": true,
+		"status":      true,
+		"config":      true,
+		"updated_at":  true,
+	},
+	"tasks": {
+		"status":          true,
+		"output":          true,
+		"error":           true,
+		"started_at":      true,
+		"completed_at":    true,
+		"callback_url":    true,
+		"callback_secret": true,
+		"signal_callback": true,
+		"last_heartbeat":  true,
+	},
+}
+
+// BuildUpdate builds an `UPDATE <table> SET col = $1, ... WHERE <whereCol>
+// = $N [AND extra...]` statement. Every key in updates is validated
+// against table's entry in AllowedColumns, and known jsonb columns are
+// marshaled to JSON explicitly. Placeholders are numbered with
+// fmt.Sprintf("$%d", n) rather than single-byte rune arithmetic, which
+// silently produced non-digit placeholders past $9. The caller appends
+// its own RETURNING clause.
+func BuildUpdate(table string, updates map[string]any, whereCol string, whereVal any, extra ...Condition) (string, []any, error) {
+	allowed, ok := AllowedColumns[table]
+	if !ok {
+		return "", nil, fmt.Errorf("sqlbuilder: no column allow-list registered for table %q", table)
+	}
+	if len(updates) == 0 {
+		return "", nil, fmt.Errorf("sqlbuilder: no columns to update for table %q", table)
+	}
+
+	columns := make([]string, 0, len(updates))
+	for col := range updates {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	setClauses := make([]string, 0, len(columns))
+	args := make([]any, 0, len(columns)+1+len(extra))
+	n := 1
+
+	for _, col := range columns {
+		if !allowed[col] {
+			return "", nil, fmt.Errorf("sqlbuilder: column %q is not updatable on table %q", col, table)
+		}
+
+		val := updates[col]
+		if jsonColumns[col] {
+			marshaled, err := json.Marshal(val)
+			if err != nil {
+				return "", nil, fmt.Errorf("sqlbuilder: marshaling column %q: %w", col, err)
+			}
+			val = marshaled
+		}
+
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col, n))
+		args = append(args, val)
+		n++
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d", table, strings.Join(setClauses, ", "), whereCol, n)
+	args = append(args, whereVal)
+	n++
+
+	for _, cond := range extra {
+		query += fmt.Sprintf(" AND %s = $%d", cond.Column, n)
+		args = append(args, cond.Value)
+		n++
+	}
+
+	return query, args, nil
+}
+
+// Filter is a single "column = value" equality condition for BuildSelect.
+// A nil or empty-string Value is skipped, so callers can build the slice unconditionally
+// from optional query parameters instead of branching on each one.
+type Filter struct {
+	Column string
+	Value  any
+}
+
+// RangeFilter is a "column >= from AND column <= to" condition for
+// BuildSelect, used for ranges such as created_between. Either bound may
+// be left as the zero time to leave that side open.
+type RangeFilter struct {
+	Column string
+	From   time.Time
+	To     time.Time
+}
+
+// BuildSelect assembles a `SELECT <columns> FROM <table> WHERE ...`
+// statement and a matching `SELECT COUNT(*) FROM <table> WHERE ...`
+// statement sharing the same WHERE clause and args, from a set of
+// equality filters and ranges. Placeholders start at $1; the returned
+// nextArg is the next unused placeholder number, so the caller can
+// append further clauses (a keyset range, ORDER BY, LIMIT) without
+// recounting args.
+func BuildSelect(table, columns string, filters []Filter, ranges []RangeFilter) (query, countQuery string, args []any, nextArg int) {
+	var clauses []string
+	args = []any{}
+	n := 1
+
+	for _, f := range filters {
+		if f.Value == nil || f.Value == "" {
+			continue
+		}
+		clauses = append(clauses, fmt.Sprintf("%s = $%d", f.Column, n))
+		args = append(args, f.Value)
+		n++
+	}
+
+	for _, r := range ranges {
+		if !r.From.IsZero() {
+			clauses = append(clauses, fmt.Sprintf("%s >= $%d", r.Column, n))
+			args = append(args, r.From)
+			n++
+		}
+		if !r.To.IsZero() {
+			clauses = append(clauses, fmt.Sprintf("%s <= $%d", r.Column, n))
+			args = append(args, r.To)
+			n++
+		}
+	}
+
+	where := "1=1"
+	if len(clauses) > 0 {
+		where = strings.Join(clauses, " AND ")
+	}
+
+	query = fmt.Sprintf("SELECT %s FROM %s WHERE %s", columns, table, where)
+	countQuery = fmt.Sprintf("SELECT COUNT(*)::int FROM %s WHERE %s", table, where)
+	return query, countQuery, args, n
+}
+
+// Cursor is an opaque keyset-pagination marker encoding the last row's
+// (created_at, id), so a page can resume with `WHERE (created_at, id) <
+// ($1, $2)` instead of OFFSET, which stays fast on deep pages.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode renders a Cursor as the opaque string returned to callers as
+// next_cursor.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses an opaque cursor string produced by Cursor.Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("sqlbuilder: invalid cursor: %w", err)
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return Cursor{}, fmt.Errorf("sqlbuilder: invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Cursor{}, fmt.Errorf("sqlbuilder: invalid cursor timestamp: %w", err)
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
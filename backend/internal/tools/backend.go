@@ -0,0 +1,18 @@
+package tools
+
+import (
+	"context"
+	"io"
+)
+
+// Backend runs a single tool invocation and streams back its stdout and
+// stderr as they're produced. wait blocks until the run finishes and
+// reports its outcome - the same two-phase contract as exec.Cmd's
+// StdoutPipe/Wait, so callers can start copying from stdout/stderr
+// before the run completes instead of buffering the whole thing first.
+//
+// Run itself should return as soon as the backend has started the work
+// and has readers attached; it must not block until completion.
+type Backend interface {
+	Run(ctx context.Context, tool ToolConfig, input []byte) (stdout, stderr io.Reader, wait func() error, err error)
+}
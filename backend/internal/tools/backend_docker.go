@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// DockerBackend runs a tool inside a short-lived container via the
+// Docker Engine API's Unix socket directly, rather than taking on
+// github.com/docker/docker/client for what's a handful of calls
+// (create, start, wait, logs) - the same hand-rolled-over-SDK choice
+// this codebase already makes for Prometheus metrics and the SSRF-safe
+// HTTP proxy.
+type DockerBackend struct {
+	httpClient *http.Client
+}
+
+// NewDockerBackend creates a DockerBackend that talks to the daemon over
+// socketPath (typically "/var/run/docker.sock").
+func NewDockerBackend(socketPath string) *DockerBackend {
+	return &DockerBackend{
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+type dockerCreateRequest struct {
+	Image      string           `json:"Image"`
+	Cmd        []string         `json:"Cmd"`
+	Env        []string         `json:"Env,omitempty"`
+	HostConfig dockerHostConfig `json:"HostConfig"`
+}
+
+type dockerHostConfig struct {
+	Binds      []string `json:"Binds,omitempty"`
+	AutoRemove bool     `json:"AutoRemove"`
+}
+
+type dockerCreateResponse struct {
+	ID string `json:"Id"`
+}
+
+// do issues a request against the Engine API, using "docker" as a
+// placeholder host since the connection is actually a Unix socket, and
+// turns any >=400 response into an error carrying the daemon's message.
+func (b *DockerBackend) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://docker"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("docker API %s %s: %s: %s", method, path, resp.Status, string(msg))
+	}
+	return resp, nil
+}
+
+func (b *DockerBackend) Run(ctx context.Context, tool ToolConfig, input []byte) (stdout, stderr io.Reader, wait func() error, err error) {
+	if tool.Image == "" {
+		return nil, nil, nil, fmt.Errorf("tool %q has no image configured for the docker backend", tool.Name)
+	}
+
+	cmd := append(append([]string{tool.Command}, tool.Args...), "--input", string(input))
+
+	var env []string
+	for k, v := range tool.Env {
+		env = append(env, k+"="+v)
+	}
+
+	createReq := dockerCreateRequest{
+		Image: tool.Image,
+		Cmd:   cmd,
+		Env:   env,
+		HostConfig: dockerHostConfig{
+			AutoRemove: true,
+		},
+	}
+	if tool.WorkingDir != "" && tool.WorkingDir != "." {
+		createReq.HostConfig.Binds = []string{tool.WorkingDir + ":/workspace:ro"}
+	}
+
+	createResp, err := b.do(ctx, http.MethodPost, "/containers/create", createReq)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var created dockerCreateResponse
+	decodeErr := json.NewDecoder(createResp.Body).Decode(&created)
+	createResp.Body.Close()
+	if decodeErr != nil {
+		return nil, nil, nil, decodeErr
+	}
+
+	// With AutoRemove set, the daemon deletes the container the instant
+	// it exits - which, for a fast-exiting tool, can easily happen before
+	// we'd otherwise get around to calling /wait. Issue the wait request
+	// with condition=next-exit now, before starting the container, so it
+	// queues up server-side and can't lose the race with auto-remove.
+	waitCh := make(chan dockerWaitResult, 1)
+	go func() {
+		waitCh <- b.awaitExit(ctx, created.ID)
+	}()
+
+	if _, err := b.do(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil); err != nil {
+		return nil, nil, nil, err
+	}
+
+	logsResp, err := b.do(ctx, http.MethodGet, "/containers/"+created.ID+"/logs?follow=true&stdout=true&stderr=true", nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	go func() {
+		demuxErr := demuxDockerStream(logsResp.Body, stdoutW, stderrW)
+		stdoutW.CloseWithError(demuxErr)
+		stderrW.CloseWithError(demuxErr)
+	}()
+
+	wait = func() error {
+		defer logsResp.Body.Close()
+
+		result := <-waitCh
+		if result.err != nil {
+			return result.err
+		}
+		if result.statusCode != 0 {
+			return fmt.Errorf("container exited with status %d", result.statusCode)
+		}
+		return nil
+	}
+
+	return stdoutR, stderrR, wait, nil
+}
+
+// dockerWaitResult is the outcome of awaitExit: either the container's
+// exit code or the error encountered while waiting for it.
+type dockerWaitResult struct {
+	statusCode int
+	err        error
+}
+
+// awaitExit blocks until containerID next exits (which may already have
+// happened by the time the caller reads the result) and returns its exit
+// code.
+func (b *DockerBackend) awaitExit(ctx context.Context, containerID string) dockerWaitResult {
+	waitResp, err := b.do(ctx, http.MethodPost, "/containers/"+containerID+"/wait?condition=next-exit", nil)
+	if err != nil {
+		return dockerWaitResult{err: err}
+	}
+	defer waitResp.Body.Close()
+
+	var result struct {
+		StatusCode int `json:"StatusCode"`
+	}
+	if err := json.NewDecoder(waitResp.Body).Decode(&result); err != nil {
+		return dockerWaitResult{err: err}
+	}
+	return dockerWaitResult{statusCode: result.StatusCode}
+}
+
+// demuxDockerStream splits the Engine API's multiplexed log stream
+// (used whenever a container is created without a TTY) back into
+// separate stdout/stderr streams. Each frame is an 8-byte header -
+// stream type in byte 0 (1 = stdout, 2 = stderr), payload size as a
+// big-endian uint32 in bytes 4-7 - followed by that many bytes of
+// payload.
+func demuxDockerStream(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[4:8])
+		dst := io.Discard
+		switch header[0] {
+		case 1:
+			dst = stdout
+		case 2:
+			dst = stderr
+		}
+		if _, err := io.CopyN(dst, r, int64(size)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// LocalBackend runs a tool as a local subprocess via Command/Args - the
+// original, and still default, way Executor ran every tool before other
+// backends existed.
+type LocalBackend struct {
+	projectRoot string
+}
+
+// NewLocalBackend creates a LocalBackend that resolves a tool's relative
+// WorkingDir against projectRoot.
+func NewLocalBackend(projectRoot string) *LocalBackend {
+	return &LocalBackend{projectRoot: projectRoot}
+}
+
+func (b *LocalBackend) Run(ctx context.Context, tool ToolConfig, input []byte) (stdout, stderr io.Reader, wait func() error, err error) {
+	args := append(append([]string{}, tool.Args...), "--input", string(input))
+	cmd := exec.CommandContext(ctx, tool.Command, args...)
+
+	workDir := tool.WorkingDir
+	if workDir == "." || workDir == "" {
+		workDir = b.projectRoot
+	} else if !filepath.IsAbs(workDir) {
+		workDir = filepath.Join(b.projectRoot, workDir)
+	}
+	cmd.Dir = workDir
+
+	cmd.Env = append(os.Environ(),
+		"LLM_PROVIDER="+os.Getenv("LLM_PROVIDER"),
+		"OPENAI_API_KEY="+os.Getenv("OPENAI_API_KEY"),
+		"ANTHROPIC_API_KEY="+os.Getenv("ANTHROPIC_API_KEY"),
+	)
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return stdoutPipe, stderrPipe, cmd.Wait, nil
+}
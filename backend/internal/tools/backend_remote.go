@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+)
+
+// RemoteWorker is one HTTP worker RemoteBackend can dispatch a tool run
+// to, advertising the labels (e.g. {"gpu": "true"}) a tool's
+// WorkerSelector is matched against.
+type RemoteWorker struct {
+	URL    string
+	Labels map[string]string
+}
+
+// RemoteBackend dispatches a tool run to whichever configured worker
+// advertises labels matching the tool's WorkerSelector, POSTing the
+// tool's JSON input (the same document LocalBackend passes as --input)
+// and streaming the worker's response body back as stdout.
+type RemoteBackend struct {
+	workers    []RemoteWorker
+	httpClient *http.Client
+}
+
+// NewRemoteBackend creates a RemoteBackend that routes across workers.
+func NewRemoteBackend(workers []RemoteWorker) *RemoteBackend {
+	return &RemoteBackend{
+		workers:    workers,
+		httpClient: &http.Client{},
+	}
+}
+
+// selectWorker returns the first configured worker whose labels match
+// every pattern in selector, so operators can order workers by
+// preference when more than one would qualify.
+func (b *RemoteBackend) selectWorker(selector map[string]string) (RemoteWorker, error) {
+	for _, worker := range b.workers {
+		if workerMatches(worker, selector) {
+			return worker, nil
+		}
+	}
+	return RemoteWorker{}, fmt.Errorf("no remote worker advertises labels matching selector %v", selector)
+}
+
+func workerMatches(worker RemoteWorker, selector map[string]string) bool {
+	for key, pattern := range selector {
+		value, ok := worker.Labels[key]
+		if !ok {
+			return false
+		}
+		matched, err := path.Match(pattern, value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *RemoteBackend) Run(ctx context.Context, tool ToolConfig, input []byte) (stdout, stderr io.Reader, wait func() error, err error) {
+	worker, err := b.selectWorker(tool.WorkerSelector)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, worker.URL, bytes.NewReader(input))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	wait = func() error {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("remote worker %s returned %s", worker.URL, resp.Status)
+		}
+		return nil
+	}
+
+	return resp.Body, bytes.NewReader(nil), wait, nil
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"bytes"
+	"sync"
+)
+
+// BroadcastEvent is one message fanned out to a task's stream
+// subscribers: either a line of stderr output as the subprocess
+// produces it, or the terminal status once the tool exits.
+type BroadcastEvent struct {
+	Event string // "log" or "status"
+	Data  string
+}
+
+// taskStreamBacklog bounds how many recent events a broadcaster keeps,
+// so a client that subscribes partway through a run still sees the
+// output that came before it connected.
+const taskStreamBacklog = 200
+
+// broadcaster fans a single task's output lines and terminal status out
+// to any number of concurrent SSE subscribers, keeping a ring buffer of
+// recent events for late joiners.
+type broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan BroadcastEvent]struct{}
+	backlog     []BroadcastEvent
+	closed      bool
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subscribers: make(map[chan BroadcastEvent]struct{})}
+}
+
+// subscribe registers a new subscriber and returns its event channel
+// along with whatever backlog is already buffered, so the caller can
+// replay it before reading further events off the channel.
+func (b *broadcaster) subscribe() (ch chan BroadcastEvent, backlog []BroadcastEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch = make(chan BroadcastEvent, taskStreamBacklog)
+	backlog = append([]BroadcastEvent{}, b.backlog...)
+	if b.closed {
+		close(ch)
+		return ch, backlog
+	}
+	b.subscribers[ch] = struct{}{}
+	return ch, backlog
+}
+
+// unsubscribe removes ch from the fan-out set. Safe to call more than
+// once and safe to call after close.
+func (b *broadcaster) unsubscribe(ch chan BroadcastEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish fans evt out to every current subscriber and appends it to
+// the ring buffer. A subscriber whose channel is full (a stalled
+// client) is dropped rather than blocking the subprocess.
+func (b *broadcaster) publish(evt BroadcastEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.backlog = append(b.backlog, evt)
+	if len(b.backlog) > taskStreamBacklog {
+		b.backlog = b.backlog[len(b.backlog)-taskStreamBacklog:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// close publishes a terminal status event, then closes and unregisters
+// every subscriber. Further publish calls are no-ops.
+func (b *broadcaster) close(status string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	evt := BroadcastEvent{Event: "status", Data: status}
+	b.backlog = append(b.backlog, evt)
+	for ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+		}
+		close(ch)
+	}
+	b.subscribers = nil
+	b.closed = true
+}
+
+// lineWriter line-buffers writes and invokes publish with each complete
+// line as soon as it arrives, so an io.Writer plugged in as cmd.Stderr
+// can stream output to a broadcaster while the subprocess is still
+// running rather than only after it exits. flush must be called once
+// the subprocess exits to emit any trailing, unterminated line.
+type lineWriter struct {
+	publish func(line string)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		idx := bytes.IndexByte(w.partial, '\n')
+		if idx < 0 {
+			break
+		}
+		w.publish(string(bytes.TrimRight(w.partial[:idx], "\r")))
+		w.partial = w.partial[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.publish(string(w.partial))
+		w.partial = nil
+	}
+}
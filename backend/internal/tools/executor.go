@@ -5,11 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"io"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-backend/internal/metrics"
+
+	"github.com/hashicorp/go-hclog"
 )
 
 // Executor manages CLI tool execution with hybrid concurrency control
@@ -19,15 +22,187 @@ type Executor struct {
 	mu               sync.RWMutex
 	defaultToolLimit int
 	projectRoot      string
+	logger           hclog.Logger
+
+	queued   int64 // jobs waiting on a semaphore
+	inFlight int64 // jobs currently running
+	rejected int64 // jobs that never ran because ctx was cancelled while queued
+
+	backends        map[string]Backend // registered by name: "local", "docker", "remote", ...
+	backendsMu      sync.RWMutex
+	backendInFlight map[string]int64 // jobs currently running per backend name
+	backendStatsMu  sync.Mutex
+
+	broadcasters   map[string]*broadcaster // live output stream per in-flight task ID
+	broadcastersMu sync.Mutex
+
+	leaseHeartbeat func(taskID string) error
+	leaseFindStale func(olderThan time.Time) ([]string, error)
+	leaseFailStale func(taskID string) error
+	leaseStop      chan struct{}
+	leaseWg        sync.WaitGroup
 }
 
-// NewExecutor creates an executor with hybrid concurrency control
-func NewExecutor(globalLimit, defaultToolLimit int, projectRoot string) *Executor {
-	return &Executor{
+// taskHeartbeatInterval is how often Execute renews a running task's
+// lease. taskLeaseStaleAfter is how long a lease can go unrenewed
+// before the supervisor treats the task as abandoned; it's a multiple
+// of the heartbeat interval so a single slow tick doesn't false-positive.
+const (
+	taskHeartbeatInterval = 60 * time.Second
+	taskLeaseStaleAfter   = 3 * taskHeartbeatInterval
+)
+
+// NewExecutor creates an executor with hybrid concurrency control. It
+// registers "local" as the default backend; RegisterBackend adds others
+// (e.g. "docker", "remote") for tools whose manifest entry opts in.
+func NewExecutor(globalLimit, defaultToolLimit int, projectRoot string, logger hclog.Logger) *Executor {
+	e := &Executor{
 		globalSemaphore:  make(chan struct{}, globalLimit),
 		toolSemaphores:   make(map[string]chan struct{}),
 		defaultToolLimit: defaultToolLimit,
 		projectRoot:      projectRoot,
+		logger:           logger,
+		backends:         make(map[string]Backend),
+		backendInFlight:  make(map[string]int64),
+		broadcasters:     make(map[string]*broadcaster),
+	}
+	e.RegisterBackend("local", NewLocalBackend(projectRoot))
+	return e
+}
+
+// RegisterBackend adds or replaces the backend tools look up by name via
+// their ToolConfig.Backend field. Call before a tool configured to use
+// that name is first executed.
+func (e *Executor) RegisterBackend(name string, backend Backend) {
+	e.backendsMu.Lock()
+	defer e.backendsMu.Unlock()
+	e.backends[name] = backend
+}
+
+// getBackend resolves a tool's configured backend name, defaulting to
+// "local" for tools that don't set one.
+func (e *Executor) getBackend(name string) (Backend, error) {
+	if name == "" {
+		name = "local"
+	}
+	e.backendsMu.RLock()
+	defer e.backendsMu.RUnlock()
+	backend, ok := e.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("no %q backend is registered", name)
+	}
+	return backend, nil
+}
+
+// addBackendInFlight adjusts the running-job count GetStats reports for
+// backend name.
+func (e *Executor) addBackendInFlight(name string, delta int64) {
+	if name == "" {
+		name = "local"
+	}
+	e.backendStatsMu.Lock()
+	defer e.backendStatsMu.Unlock()
+	e.backendInFlight[name] += delta
+}
+
+// Subscribe streams live stderr lines and the terminal status for
+// taskID as Execute produces them, replaying whatever backlog the
+// broadcaster already has buffered. unsubscribe must be called once the
+// caller stops reading, typically via defer.
+func (e *Executor) Subscribe(taskID string) (events <-chan BroadcastEvent, backlog []BroadcastEvent, unsubscribe func()) {
+	b := e.taskBroadcaster(taskID)
+	ch, backlog := b.subscribe()
+	return ch, backlog, func() { b.unsubscribe(ch) }
+}
+
+// taskBroadcaster returns the broadcaster for taskID, creating one if
+// this is the first subscriber or publisher to reference it.
+func (e *Executor) taskBroadcaster(taskID string) *broadcaster {
+	e.broadcastersMu.Lock()
+	defer e.broadcastersMu.Unlock()
+	b, ok := e.broadcasters[taskID]
+	if !ok {
+		b = newBroadcaster()
+		e.broadcasters[taskID] = b
+	}
+	return b
+}
+
+// finishTaskBroadcast closes out taskID's broadcaster with a terminal
+// status event and drops it from the map; a later Subscribe for the
+// same task ID starts a fresh, empty broadcaster.
+func (e *Executor) finishTaskBroadcast(taskID, status string) {
+	e.broadcastersMu.Lock()
+	b, ok := e.broadcasters[taskID]
+	delete(e.broadcasters, taskID)
+	e.broadcastersMu.Unlock()
+	if ok {
+		b.close(status)
+	}
+}
+
+// ConfigureLeaseSupervisor wires the callbacks Execute and
+// StartLeaseSupervisor use to renew and expire a task's lease, without
+// this package depending on internal/models - which itself imports
+// internal/tools for the tool registry, so the dependency can't run the
+// other way. heartbeat renews a running task's lease; findStale returns
+// the IDs of processing tasks whose lease has gone stale as of
+// olderThan; failStale marks one of those IDs failed.
+func (e *Executor) ConfigureLeaseSupervisor(heartbeat func(taskID string) error, findStale func(olderThan time.Time) ([]string, error), failStale func(taskID string) error) {
+	e.leaseHeartbeat = heartbeat
+	e.leaseFindStale = findStale
+	e.leaseFailStale = failStale
+}
+
+// StartLeaseSupervisor runs a background loop, ticking every
+// taskLeaseStaleAfter, that fails tasks whose lease has gone stale -
+// left processing with no recent heartbeat because the backend instance
+// running them crashed or was killed. A no-op if
+// ConfigureLeaseSupervisor was never called.
+func (e *Executor) StartLeaseSupervisor() {
+	if e.leaseFindStale == nil || e.leaseFailStale == nil {
+		return
+	}
+
+	e.leaseStop = make(chan struct{})
+	e.leaseWg.Add(1)
+	go func() {
+		defer e.leaseWg.Done()
+		ticker := time.NewTicker(taskLeaseStaleAfter)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				e.reapStaleTasks()
+			case <-e.leaseStop:
+				return
+			}
+		}
+	}()
+}
+
+// StopLeaseSupervisor signals the stale-task scan loop to exit and
+// waits for it to finish. A no-op if StartLeaseSupervisor was never
+// called.
+func (e *Executor) StopLeaseSupervisor() {
+	if e.leaseStop == nil {
+		return
+	}
+	close(e.leaseStop)
+	e.leaseWg.Wait()
+}
+
+func (e *Executor) reapStaleTasks() {
+	staleIDs, err := e.leaseFindStale(time.Now().Add(-taskLeaseStaleAfter))
+	if err != nil {
+		e.logger.Error("failed to scan for stale task leases", "error", err)
+		return
+	}
+	for _, id := range staleIDs {
+		if err := e.leaseFailStale(id); err != nil {
+			e.logger.Error("failed to fail stale task", "task_id", id, "error", err)
+		}
 	}
 }
 
@@ -72,28 +247,96 @@ type ExecuteOutput struct {
 	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
-// Execute runs a CLI tool with the given input
-// It applies both global and per-tool concurrency limits
-func (e *Executor) Execute(ctx context.Context, tool ToolConfig, input map[string]any) (*ExecuteOutput, error) {
-	toolSem := e.getToolSemaphore(tool.Name, tool.ConcurrencyLimit)
+// acquire blocks until both the per-tool and global semaphores admit the
+// caller, or ctx is cancelled first. The returned release func must be
+// called to free both slots.
+func (e *Executor) acquire(ctx context.Context, toolSem chan struct{}) (release func(), err error) {
+	atomic.AddInt64(&e.queued, 1)
+	defer atomic.AddInt64(&e.queued, -1)
 
 	// Acquire per-tool semaphore first
 	select {
 	case toolSem <- struct{}{}:
-		defer func() { <-toolSem }()
 	case <-ctx.Done():
+		atomic.AddInt64(&e.rejected, 1)
 		return nil, ctx.Err()
 	}
 
 	// Then acquire global semaphore
 	select {
 	case e.globalSemaphore <- struct{}{}:
-		defer func() { <-e.globalSemaphore }()
 	case <-ctx.Done():
+		<-toolSem
+		atomic.AddInt64(&e.rejected, 1)
 		return nil, ctx.Err()
 	}
 
-	log.Printf("[Executor] Starting tool: %s", tool.Name)
+	atomic.AddInt64(&e.inFlight, 1)
+	return func() {
+		atomic.AddInt64(&e.inFlight, -1)
+		<-e.globalSemaphore
+		<-toolSem
+	}, nil
+}
+
+// Execute runs a CLI tool with the given input
+// It applies both global and per-tool concurrency limits. taskID keys
+// the live output broadcaster that GET /api/agents/tasks/:id/stream
+// subscribes to, so callers should pass the task's own ID.
+func (e *Executor) Execute(ctx context.Context, taskID string, tool ToolConfig, input map[string]any) (output *ExecuteOutput, err error) {
+	toolSem := e.getToolSemaphore(tool.Name, tool.ConcurrencyLimit)
+
+	release, err := e.acquire(ctx, toolSem)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	defer func() {
+		metrics.ExecutionDuration.Observe(time.Since(start).Seconds())
+		metrics.TaskOutcomes.Inc(tool.AgentType, executionOutcome(output, err))
+	}()
+
+	b := e.taskBroadcaster(taskID)
+	defer func() {
+		status := "completed"
+		switch {
+		case err != nil:
+			status = "error"
+		case output != nil && output.Status == "failed":
+			status = "failed"
+		}
+		e.finishTaskBroadcast(taskID, status)
+	}()
+
+	logger := e.logger.With("tool", tool.Name, "task_id", taskID)
+
+	if e.leaseHeartbeat != nil {
+		stopHeartbeat := make(chan struct{})
+		defer close(stopHeartbeat)
+		go func() {
+			ticker := time.NewTicker(taskHeartbeatInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := e.leaseHeartbeat(taskID); err != nil {
+						logger.Error("failed to renew lease", "error", err)
+					}
+				case <-stopHeartbeat:
+					return
+				}
+			}
+		}()
+	}
+
+	logger.Info("starting tool")
+
+	backend, err := e.getBackend(tool.Backend)
+	if err != nil {
+		return nil, err
+	}
 
 	// Marshal input to JSON
 	inputJSON, err := json.Marshal(input)
@@ -101,9 +344,6 @@ func (e *Executor) Execute(ctx context.Context, tool ToolConfig, input map[strin
 		return nil, fmt.Errorf("failed to marshal input: %w", err)
 	}
 
-	// Build command
-	args := append(tool.Args, "--input", string(inputJSON))
-
 	// Create context with timeout
 	execCtx := ctx
 	if tool.Timeout > 0 {
@@ -112,40 +352,39 @@ func (e *Executor) Execute(ctx context.Context, tool ToolConfig, input map[strin
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(execCtx, tool.Command, args...)
+	e.addBackendInFlight(tool.Backend, 1)
+	defer e.addBackendInFlight(tool.Backend, -1)
 
-	// Set working directory
-	workDir := tool.WorkingDir
-	if workDir == "." || workDir == "" {
-		workDir = e.projectRoot
-	} else if !filepath.IsAbs(workDir) {
-		workDir = filepath.Join(e.projectRoot, workDir)
+	stdoutR, stderrR, waitFn, err := backend.Run(execCtx, tool, inputJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start tool via %q backend: %w", tool.Backend, err)
 	}
-	cmd.Dir = workDir
-
-	// Set environment variables
-	cmd.Env = append(os.Environ(),
-		"LLM_PROVIDER="+os.Getenv("LLM_PROVIDER"),
-		"OPENAI_API_KEY="+os.Getenv("OPENAI_API_KEY"),
-		"ANTHROPIC_API_KEY="+os.Getenv("ANTHROPIC_API_KEY"),
-	)
 
-	// Capture stdout and stderr
+	// Capture stdout and stderr, also fanning stderr out line-by-line to
+	// any SSE subscribers as the backend produces it
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	lw := &lineWriter{publish: func(line string) { b.publish(BroadcastEvent{Event: "log", Data: line}) }}
 
-	// Run command
-	err = cmd.Run()
+	stdoutDone := make(chan struct{})
+	go func() {
+		io.Copy(&stdout, stdoutR)
+		close(stdoutDone)
+	}()
+	io.Copy(io.MultiWriter(&stderr, lw), stderrR)
+	<-stdoutDone
+	lw.flush()
+
+	err = waitFn()
 
 	// Log stderr (this is where Python logs go)
 	if stderr.Len() > 0 {
-		log.Printf("[Executor] %s stderr:\n%s", tool.Name, stderr.String())
+		logger.Debug("tool stderr", "stderr", stderr.String())
 	}
 
 	if err != nil {
 		// Check for context deadline exceeded
 		if execCtx.Err() == context.DeadlineExceeded {
+			logger.Warn("tool execution timed out", "timeout", tool.Timeout.String())
 			return nil, fmt.Errorf("tool execution timed out after %v", tool.Timeout)
 		}
 
@@ -172,10 +411,27 @@ func (e *Executor) Execute(ctx context.Context, tool ToolConfig, input map[strin
 		}, nil
 	}
 
-	log.Printf("[Executor] Tool %s completed with status: %s", tool.Name, result.Status)
+	logger.Info("tool completed", "status", result.Status)
 	return &result, nil
 }
 
+// executionOutcome labels a finished Execute call for TaskOutcomes: a
+// transport/timeout error is labeled "error", distinct from a
+// tool-reported failure ("failed"), so operators can tell the two apart
+// on a dashboard.
+func executionOutcome(result *ExecuteOutput, err error) string {
+	if err != nil {
+		return "error"
+	}
+	if result == nil {
+		return "unknown"
+	}
+	if result.Status == "failed" {
+		return "failed"
+	}
+	return "completed"
+}
+
 // GetStats returns current executor statistics
 func (e *Executor) GetStats() map[string]any {
 	e.mu.RLock()
@@ -192,11 +448,39 @@ func (e *Executor) GetStats() map[string]any {
 		}
 	}
 
+	e.backendStatsMu.Lock()
+	backendStats := make(map[string]int64, len(e.backendInFlight))
+	for name, inFlight := range e.backendInFlight {
+		backendStats[name] = inFlight
+	}
+	e.backendStatsMu.Unlock()
+
 	return map[string]any{
 		"global": map[string]int{
 			"used":     globalUsed,
 			"capacity": globalCap,
 		},
-		"tools": toolStats,
+		"tools":     toolStats,
+		"backends":  backendStats,
+		"queued":    atomic.LoadInt64(&e.queued),
+		"in_flight": atomic.LoadInt64(&e.inFlight),
+		"rejected":  atomic.LoadInt64(&e.rejected),
+	}
+}
+
+// RefreshConcurrencyMetrics updates the agentx_tool_concurrency gauge
+// from the current semaphore state, so a Prometheus scrape always
+// reflects live usage rather than whatever it was the last time a tool
+// ran.
+func (e *Executor) RefreshConcurrencyMetrics() {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	metrics.ToolConcurrency.Set(float64(len(e.globalSemaphore)), "_global", "used")
+	metrics.ToolConcurrency.Set(float64(cap(e.globalSemaphore)), "_global", "capacity")
+
+	for name, sem := range e.toolSemaphores {
+		metrics.ToolConcurrency.Set(float64(len(sem)), name, "used")
+		metrics.ToolConcurrency.Set(float64(cap(sem)), name, "capacity")
 	}
 }
@@ -1,82 +1,228 @@
 package tools
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 )
 
 // ToolConfig defines a CLI tool that can be executed
 type ToolConfig struct {
-	Name             string        `json:"name"`
-	Description      string        `json:"description"`
-	Command          string        `json:"command"`     // e.g., "python"
-	Args             []string      `json:"args"`        // e.g., ["agents/market_research_agent/cli.py"]
-	Timeout          time.Duration `json:"timeout"`     // Max execution time
-	WorkingDir       string        `json:"working_dir"` // Working directory for the command
-	ConcurrencyLimit int           `json:"concurrency"` // Per-tool concurrency limit (0 = use default)
-	AgentType        string        `json:"agent_type"`  // Maps to agent type in DB (e.g., "market_research")
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	Command          string            `json:"command"`          // e.g., "python"
+	Args             []string          `json:"args"`             // e.g., ["agents/market_research_agent/cli.py"]
+	Timeout          time.Duration     `json:"timeout"`          // Max execution time
+	WorkingDir       string            `json:"working_dir"`      // Working directory for the command
+	ConcurrencyLimit int               `json:"concurrency"`      // Per-tool concurrency limit (0 = use default)
+	AgentType        string            `json:"agent_type"`       // Maps to agent type in DB (e.g., "market_research")
+	Env              map[string]string `json:"env"`              // extra environment variables
+	HealthCheckCmd   string            `json:"health_check_cmd"` // optional health-check command; stored with the registry, not run by Executor
+
+	// Backend picks which Executor backend runs this tool: "local" (the
+	// default, a subprocess via Command/Args), "docker" (a container
+	// from Image), or "remote" (an HTTP worker matching WorkerSelector).
+	Backend string `json:"backend"`
+	// Image is the container image DockerBackend starts for this tool.
+	// Only meaningful when Backend is "docker".
+	Image string `json:"image"`
+	// WorkerSelector is a set of glob patterns RemoteBackend matches
+	// against a worker's advertised labels to pick which worker runs
+	// this tool (e.g. {"gpu": "true"} only matches a worker advertising
+	// gpu=true). Only meaningful when Backend is "remote".
+	WorkerSelector map[string]string `json:"worker_selector"`
+}
+
+// manifestEntry is the on-disk shape of a single tools_manifest.json
+// entry. Timeout is a duration string ("120s") since that's the
+// readable, hand-editable form an operator writes; loadManifest parses
+// it into the ToolConfig's time.Duration.
+type manifestEntry struct {
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	Command          string            `json:"command"`
+	Args             []string          `json:"args"`
+	Timeout          string            `json:"timeout"`
+	WorkingDir       string            `json:"working_dir"`
+	ConcurrencyLimit int               `json:"concurrency"`
+	AgentType        string            `json:"agent_type"`
+	Env              map[string]string `json:"env"`
+	HealthCheckCmd   string            `json:"health_check_cmd"`
+	Backend          string            `json:"backend"`
+	Image            string            `json:"image"`
+	WorkerSelector   map[string]string `json:"worker_selector"`
+}
+
+var (
+	registryMu sync.RWMutex
+	// Registry holds all available tools, keyed by name.
+	// Tool names are used in API paths: /api/agents/{name}/execute
+	Registry = map[string]ToolConfig{}
+
+	manifestPath string
+)
+
+// InitRegistry loads the tool registry from a JSON manifest file at path
+// (see database/tools_manifest.json for the shape), the same
+// JSON-master-file pattern as seed.SeedMccCodes uses for MCC codes. This
+// should be called from main.go after loading configuration; call
+// ReloadRegistry later (e.g. on SIGHUP) to pick up manifest edits without
+// recompiling or restarting.
+func InitRegistry(path string) error {
+	tools, err := loadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	manifestPath = path
+	Registry = tools
+	registryMu.Unlock()
+
+	return nil
+}
+
+// InitRegistryFromTools sets the registry directly from a pre-loaded set
+// of tools, bypassing the manifest file entirely. This is the fallback
+// path for when the manifest is missing or invalid but a prior
+// successful load was persisted (see ToolRegistryRepository.SyncAll):
+// better to serve last-known-good tools than fail to start. ReloadRegistry
+// isn't usable afterward, since there's no manifest path to re-read.
+func InitRegistryFromTools(toolList []ToolConfig) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	result := make(map[string]ToolConfig, len(toolList))
+	for _, tool := range toolList {
+		result[tool.Name] = tool
+	}
+	Registry = result
+}
+
+// ReloadRegistry re-reads the manifest InitRegistry was given and diffs
+// the result against the current registry, returning the names of tools
+// that were added and removed. In-flight executions keep using the
+// ToolConfig they already hold; only future lookups see the new
+// registry.
+func ReloadRegistry() (added, removed []string, err error) {
+	registryMu.RLock()
+	path := manifestPath
+	registryMu.RUnlock()
+	if path == "" {
+		return nil, nil, fmt.Errorf("tools registry was never initialized from a manifest")
+	}
+
+	fresh, err := loadManifest(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for name := range fresh {
+		if _, existed := Registry[name]; !existed {
+			added = append(added, name)
+		}
+	}
+	for name := range Registry {
+		if _, stillPresent := fresh[name]; !stillPresent {
+			removed = append(removed, name)
+		}
+	}
+	Registry = fresh
+
+	return added, removed, nil
 }
 
-// Registry holds all available tools
-// Tool names are used in API paths: /api/agents/{name}/execute
-var Registry = map[string]ToolConfig{}
-
-// InitRegistry initializes the tool registry with configurable timeouts
-// This should be called from main.go after loading configuration
-func InitRegistry(marketResearchTimeout, salesAgentTimeout, blogAgentTimeout time.Duration) {
-	Registry = map[string]ToolConfig{
-		"market_research": {
-			Name:             "Market Research Agent",
-			Description:      "Comprehensive site scan, competitor analysis, and compliance monitoring",
-			Command:          "python3",
-			Args:             []string{"backend/agents/market_research_agent/cli.py"},
-			Timeout:          marketResearchTimeout,
-			WorkingDir:       ".",
-			ConcurrencyLimit: 5,
-			AgentType:        "market_research",
-		},
-		"sales": {
-			Name:             "Sales Agent",
-			Description:      "Lead qualification, email outreach, and meeting scheduling automation",
-			Command:          "python3",
-			Args:             []string{"backend/agents/sales_agent/cli.py"},
-			Timeout:          salesAgentTimeout,
-			WorkingDir:       ".",
-			ConcurrencyLimit: 5,
-			AgentType:        "sales",
-		},
-		"blog": {
-			Name:             "Blog Agent",
-			Description:      "Generates structured blog outlines and drafts for marketing teams",
-			Command:          "python3",
-			Args:             []string{"backend/agents/blog_agent/cli.py"},
-			Timeout:          blogAgentTimeout,
-			WorkingDir:       ".",
-			ConcurrencyLimit: 5,
-			AgentType:        "blog",
-		},
-		// Add more tools here as needed...
-		// Example:
-		// "seo-analyzer": {
-		//     Name:             "SEO Analyzer",
-		//     Description:      "Analyze website SEO and provide recommendations",
-		//     Command:          "python",
-		//     Args:             []string{"agents/seo_analyzer_agent/cli.py"},
-		//     Timeout:          2 * time.Minute,
-		//     WorkingDir:       ".",
-		//     ConcurrencyLimit: 3,
-		//     AgentType:        "seo-analyzer",
-		// },
+func loadManifest(path string) (map[string]ToolConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []manifestEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("invalid tools manifest %s: %w", path, err)
 	}
+
+	result := make(map[string]ToolConfig, len(entries))
+	for i, entry := range entries {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("tools manifest entry %d: name is required", i)
+		}
+		if entry.Command == "" {
+			return nil, fmt.Errorf("tools manifest entry %q: command is required", entry.Name)
+		}
+		if entry.AgentType == "" {
+			return nil, fmt.Errorf("tools manifest entry %q: agent_type is required", entry.Name)
+		}
+		if _, dup := result[entry.Name]; dup {
+			return nil, fmt.Errorf("tools manifest entry %q: duplicate name", entry.Name)
+		}
+
+		backend := entry.Backend
+		if backend == "" {
+			backend = "local"
+		}
+		if backend == "docker" && entry.Image == "" {
+			return nil, fmt.Errorf("tools manifest entry %q: image is required for the docker backend", entry.Name)
+		}
+
+		timeout := 2 * time.Minute
+		if entry.Timeout != "" {
+			parsed, err := time.ParseDuration(entry.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("tools manifest entry %q: invalid timeout %q: %w", entry.Name, entry.Timeout, err)
+			}
+			timeout = parsed
+		}
+
+		workingDir := entry.WorkingDir
+		if workingDir == "" {
+			workingDir = "."
+		}
+
+		result[entry.Name] = ToolConfig{
+			Name:             entry.Name,
+			Description:      entry.Description,
+			Command:          entry.Command,
+			Args:             entry.Args,
+			Timeout:          timeout,
+			WorkingDir:       workingDir,
+			ConcurrencyLimit: entry.ConcurrencyLimit,
+			AgentType:        entry.AgentType,
+			Env:              entry.Env,
+			HealthCheckCmd:   entry.HealthCheckCmd,
+			Backend:          backend,
+			Image:            entry.Image,
+			WorkerSelector:   entry.WorkerSelector,
+		}
+	}
+
+	return result, nil
 }
 
 // GetTool retrieves a tool by name
 func GetTool(name string) (ToolConfig, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	tool, exists := Registry[name]
 	return tool, exists
 }
 
 // ListTools returns all available tools
 func ListTools() []ToolConfig {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	tools := make([]ToolConfig, 0, len(Registry))
 	for _, tool := range Registry {
 		tools = append(tools, tool)
@@ -86,6 +232,8 @@ func ListTools() []ToolConfig {
 
 // GetToolByAgentType finds a tool by its agent type (used for backward compatibility)
 func GetToolByAgentType(agentType string) (ToolConfig, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 	for _, tool := range Registry {
 		if tool.AgentType == agentType {
 			return tool, true